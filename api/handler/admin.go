@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/becaraya/katana-api/api/middleware"
+	"github.com/becaraya/katana-api/internal/account"
+	"github.com/becaraya/katana-api/internal/game"
+	"github.com/gin-gonic/gin"
+)
+
+type SetRolesRequest struct {
+	Roles []string `json:"roles" binding:"required"`
+}
+
+// ListSessions retourne les tokens en cours de validité et les utilisateurs connectés
+func ListSessions(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"sessions":        tokenStore.GetTokens(),
+		"connected_users": middleware.GetConnectedUsernames(),
+	})
+}
+
+// EndGame met fin à une partie en cours sur décision d'un modérateur
+func EndGame(c *gin.Context) {
+	g := game.GetGameManager().GetGame(c.Param("id"))
+	if g == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Game not found"})
+		return
+	}
+	if !g.Abort(game.EndReasonModerator) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Game is not in progress"})
+		return
+	}
+
+	middleware.BroadcastGameUpdate(g, game.EventGameEnded)
+	c.JSON(http.StatusOK, gin.H{"game": g.View("")})
+}
+
+// KickUser déconnecte un utilisateur et le retire de ses parties
+func KickUser(c *gin.Context) {
+	username := c.Param("username")
+	c.JSON(http.StatusOK, gin.H{
+		"username":    username,
+		"kicked_from": kick(username),
+	})
+}
+
+// BanUser bannit un compte : il ne peut plus se connecter ni rafraîchir ses tokens
+func BanUser(c *gin.Context) {
+	setBanned(c, true)
+}
+
+// UnbanUser lève le bannissement d'un compte
+func UnbanUser(c *gin.Context) {
+	setBanned(c, false)
+}
+
+func setBanned(c *gin.Context, banned bool) {
+	username := c.Param("username")
+	if account.IsGuest(username) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "guests have no account, kick them instead"})
+		return
+	}
+
+	err := accountStore.Update(username, func(updated *account.Account) error {
+		updated.Banned = banned
+		return nil
+	})
+	if err != nil {
+		respondAccountError(c, err)
+		return
+	}
+
+	response := gin.H{"username": username, "banned": banned}
+	if banned {
+		response["kicked_from"] = kick(username)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// SetUserRoles remplace les rôles d'un compte. Ses tokens sont révoqués pour
+// que les nouveaux rôles s'appliquent dès sa prochaine connexion.
+func SetUserRoles(c *gin.Context) {
+	var req SetRolesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for _, role := range req.Roles {
+		if !slices.Contains(middleware.Roles, role) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown role: " + role})
+			return
+		}
+	}
+
+	username := c.Param("username")
+	err := accountStore.Update(username, func(updated *account.Account) error {
+		updated.Roles = req.Roles
+		return nil
+	})
+	if err != nil {
+		respondAccountError(c, err)
+		return
+	}
+	tokenStore.RevokeUser(username)
+
+	c.JSON(http.StatusOK, gin.H{"username": username, "roles": req.Roles})
+}
+
+// GetHiddenGameState retourne l'état complet d'une partie terminée, rôles et mains compris
+func GetHiddenGameState(c *gin.Context) {
+	id := c.Param("id")
+	if entry, exists := game.GetGameManager().GetArchive().Get(id); exists {
+		c.JSON(http.StatusOK, entry)
+		return
+	}
+
+	g := game.GetGameManager().GetGame(id)
+	if g == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Game not found"})
+		return
+	}
+	if g.GetState() != game.GameStateEnded {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Hidden state is only available once the game has ended"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": g.ID, "snapshot": g.Snapshot()})
+}
+
+// kick révoque les tokens d'un utilisateur, ce qui ferme ses WebSockets, et
+// libère ses sièges. Retourne les parties dont il a été retiré.
+func kick(username string) []string {
+	tokenStore.RevokeUser(username)
+
+	kickedFrom := []string{}
+	for _, g := range game.GetGameManager().GetGames() {
+		event := game.EventSeatReleased
+		if g.GetState() == game.GameStateWaiting {
+			event = game.EventPlayerLeft
+		}
+		if g.Kick(username) {
+			kickedFrom = append(kickedFrom, g.ID)
+			middleware.UnsubscribeUser(username, g.ID)
+			middleware.BroadcastGameUpdate(g, event)
+		}
+	}
+	return kickedFrom
+}
+
+func respondAccountError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, account.ErrAccountNotFound) {
+		status = http.StatusNotFound
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
@@ -1,20 +1,87 @@
 package handler
 
 import (
-	"encoding/json"
+	"errors"
 	"net/http"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/becaraya/katana-api/api/middleware"
+	"github.com/becaraya/katana-api/internal/account"
 	"github.com/becaraya/katana-api/internal/bootstrap"
 	"github.com/gin-gonic/gin"
 )
 
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
 }
 
-var tokenStore = middleware.NewTokenStore()
+type RegisterRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type GuestLoginRequest struct {
+	Username string `json:"username" binding:"required"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type TokenResponse struct {
+	Token        string    `json:"token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	Username     string    `json:"username"`
+	Guest        bool      `json:"guest"`
+	Roles        []string  `json:"roles"`
+}
+
+// tokenStore est le registre des tokens émis et révoqués, partagé avec le
+// middleware d'authentification qui le fournit au démarrage
+var tokenStore *middleware.TokenStore
+
+// SetTokenStore fournit le registre des tokens émis et révoqués
+func SetTokenStore(store *middleware.TokenStore) {
+	tokenStore = store
+}
+
+var accountStore account.Store = account.NewMemoryStore()
+
+// SetAccountStore remplace le stockage des comptes
+func SetAccountStore(store account.Store) {
+	accountStore = store
+}
+
+// Register crée un compte puis connecte son propriétaire
+func Register(env *bootstrap.Env) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req RegisterRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		created, err := account.New(req.Username, req.Password)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := accountStore.Create(created); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, account.ErrUsernameTaken) {
+				status = http.StatusConflict
+			}
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+
+		issueToken(c, env, created.Username, false)
+	}
+}
 
 func Login(env *bootstrap.Env) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -24,23 +91,183 @@ func Login(env *bootstrap.Env) gin.HandlerFunc {
 			return
 		}
 
-		// Generate JWT token
-		expiry := time.Now().Add(24 * time.Hour)
-		tokenString, err := middleware.GenerateToken(loginReq.Username, env.AccessTokenSecret, 24*time.Hour)
+		found, err := account.Authenticate(accountStore, loginReq.Username, loginReq.Password)
+		if err != nil {
+			status := http.StatusUnauthorized
+			if errors.Is(err, account.ErrAccountBanned) {
+				status = http.StatusForbidden
+			}
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+
+		issueToken(c, env, found.Username, false)
+	}
+}
+
+// guestSuffixLength est le nombre de caractères hexadécimaux tirés au hasard
+// à la fin du nom d'un invité
+const guestSuffixLength = 12
+
+// GuestLogin connecte un joueur sans compte, sous un nom préfixé par guest-
+// et suivi d'un suffixe aléatoire : deux invités qui choisissent le même nom
+// restent deux identités distinctes, et aucun ne peut prendre la place de l'autre
+func GuestLogin(env *bootstrap.Env) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req GuestLoginRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		name := req.Username
+		if account.IsGuest(name) {
+			name = name[len(account.GuestPrefix):]
+		}
+		if err := account.ValidateUsername(name); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		suffix, err := middleware.NewTokenID()
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not generate token"})
 			return
 		}
 
-		// Store token in memory
-		tokenStore.AddToken(loginReq.Username, expiry)
+		issueToken(c, env, account.GuestPrefix+strings.ToLower(name)+"-"+suffix[:guestSuffixLength], true)
+	}
+}
+
+// Refresh échange un refresh token contre une nouvelle paire de tokens.
+// Le refresh token présenté est consommé : le rejouer révoque la session.
+func Refresh(env *bootstrap.Env) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req RefreshRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		claims, err := middleware.ValidateToken(req.RefreshToken, env.RefreshTokenSecret)
+		if err == nil && claims.Kind != middleware.TokenKindRefresh {
+			err = middleware.ErrTokenInvalid
+		}
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
 
-		// Broadcast new user
-		message, _ := json.Marshal(map[string]string{"username": loginReq.Username, "expiry": expiry.String()})
-		middleware.BroadcastMessage(message)
+		info, err := tokenStore.Redeem(claims.ID)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		if found, exists := accountStore.Get(claims.Username); exists && found.Banned {
+			tokenStore.RevokeUser(claims.Username)
+			c.JSON(http.StatusForbidden, gin.H{"error": account.ErrAccountBanned.Error()})
+			return
+		}
 
-		c.JSON(http.StatusOK, gin.H{"token": tokenString})
+		pair, err := generateTokenPair(env, info.Session, claims.Username, claims.Guest)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not generate token"})
+			return
+		}
+		c.JSON(http.StatusOK, pair)
+	}
+}
+
+// Logout révoque l'access token courant et la session dont il est issu
+func Logout(c *gin.Context) {
+	claims := c.MustGet("claims").(*middleware.Claims)
+	tokenStore.Revoke(claims.Info())
+	c.JSON(http.StatusOK, gin.H{"message": "logged out"})
+}
+
+// issueToken ouvre une session pour un utilisateur authentifié et annonce sa connexion
+func issueToken(c *gin.Context, env *bootstrap.Env, username string, guest bool) {
+	session, err := middleware.NewTokenID()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not generate token"})
+		return
+	}
+
+	pair, err := generateTokenPair(env, session, username, guest)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not generate token"})
+		return
+	}
+
+	// Annoncer la connexion dans le salon d'accueil
+	middleware.PublishToRooms(middleware.NewEnvelope(middleware.MessageLogin, middleware.LoginPayload{
+		Username: username,
+		Expiry:   pair.ExpiresAt,
+	}), middleware.LobbyRoom)
+
+	c.JSON(http.StatusOK, pair)
+}
+
+// generateTokenPair signe un access token et un refresh token pour une
+// session, et les enregistre dans le registre des tokens
+func generateTokenPair(env *bootstrap.Env, session string, username string, guest bool) (*TokenResponse, error) {
+	roles := rolesFor(env, username, guest)
+	accessToken, access, err := middleware.GenerateToken(username, guest, roles, middleware.TokenKindAccess,
+		env.AccessTokenSecret, time.Duration(env.AccessTokenExpiryHour)*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, refresh, err := middleware.GenerateToken(username, guest, roles, middleware.TokenKindRefresh,
+		env.RefreshTokenSecret, time.Duration(env.RefreshTokenExpiryHour)*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	accessInfo := access.Info()
+	accessInfo.Session = session
+	refreshInfo := refresh.Info()
+	refreshInfo.Session = session
+	tokenStore.AddToken(accessInfo)
+	tokenStore.AddToken(refreshInfo)
+
+	return &TokenResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    accessInfo.ExpiresAt,
+		Username:     username,
+		Guest:        guest,
+		Roles:        roles,
+	}, nil
+}
+
+// rolesFor retourne les rôles d'un utilisateur : les invités ne sont que
+// joueurs, les comptes ont ceux de leur fiche, et ceux listés dans
+// ADMIN_USERNAMES sont administrateurs
+func rolesFor(env *bootstrap.Env, username string, guest bool) []string {
+	roles := []string{middleware.RolePlayer}
+	if guest {
+		return roles
+	}
+	if found, exists := accountStore.Get(username); exists {
+		roles = addRoles(roles, found.Roles...)
+	}
+	for _, admin := range env.AdminUsernames {
+		if strings.EqualFold(strings.TrimSpace(admin), username) {
+			roles = addRoles(roles, middleware.RoleAdmin)
+			break
+		}
+	}
+	return roles
+}
+
+// addRoles ajoute des rôles à une liste sans doublon
+func addRoles(roles []string, added ...string) []string {
+	for _, role := range added {
+		if !slices.Contains(roles, role) {
+			roles = append(roles, role)
+		}
 	}
+	return roles
 }
 
 func ListTokens(c *gin.Context) {
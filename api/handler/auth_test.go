@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/becaraya/katana-api/internal/account"
+	"github.com/becaraya/katana-api/internal/game"
+	"github.com/gin-gonic/gin"
+)
+
+// guestLogin connecte un invité et retourne son token et son nom
+func guestLogin(t *testing.T, router *gin.Engine, name string) (string, string) {
+	t.Helper()
+	rec := perform(router, "/login/guest", "", GuestLoginRequest{Username: name})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("guest login %q: got %d: %s", name, rec.Code, rec.Body.String())
+	}
+	var response TokenResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("guest login response: %v", err)
+	}
+	return response.Token, response.Username
+}
+
+func TestGuestLoginCannotTakeOverAnotherGuest(t *testing.T) {
+	router, _ := newTestRouter()
+	first, firstName := guestLogin(t, router, "Samurai")
+	second, secondName := guestLogin(t, router, "samurai")
+	removeOnCleanup(t, firstName, secondName)
+
+	if !strings.HasPrefix(firstName, account.GuestPrefix+"samurai-") || !strings.HasPrefix(secondName, account.GuestPrefix+"samurai-") {
+		t.Fatalf("guest names %q and %q do not keep the chosen name", firstName, secondName)
+	}
+	if firstName == secondName {
+		t.Fatalf("two guests named samurai share the identity %q", firstName)
+	}
+
+	rec := perform(router, "/game/join", first, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("first guest join: got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := perform(router, "/game/leave", second, LeaveGameRequest{Username: firstName}); rec.Code != http.StatusForbidden {
+		t.Fatalf("second guest leave for the first: got %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if rec := perform(router, "/game/command", second, game.Command{Type: game.CommandEndTurn, Player: firstName}); rec.Code != http.StatusForbidden {
+		t.Fatalf("second guest command for the first: got %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if !game.GetGameManager().GetCurrentGame().HasPlayer(firstName) {
+		t.Fatal("the first guest lost their seat to the second")
+	}
+}
+
+func TestGuestCanStartGame(t *testing.T) {
+	router, _ := newTestRouter()
+	var tokens []string
+	for i := 0; i < game.MinPlayers; i++ {
+		token, name := guestLogin(t, router, "ronin")
+		removeOnCleanup(t, name)
+		if rec := perform(router, "/game/join", token, nil); rec.Code != http.StatusOK {
+			t.Fatalf("guest join: got %d: %s", rec.Code, rec.Body.String())
+		}
+		tokens = append(tokens, token)
+	}
+	current := game.GetGameManager().GetCurrentGame()
+	t.Cleanup(func() {
+		current.Abort(game.EndReasonModerator)
+		game.GetGameManager().CreateGame("test")
+	})
+
+	if rec := perform(router, "/game/start", tokens[1], nil); rec.Code != http.StatusOK {
+		t.Fatalf("start by a seated guest: got %d: %s", rec.Code, rec.Body.String())
+	}
+}
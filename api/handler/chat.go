@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/becaraya/katana-api/api/middleware"
+	"github.com/becaraya/katana-api/internal/chat"
+	"github.com/becaraya/katana-api/internal/game"
+	"github.com/gin-gonic/gin"
+)
+
+type ReportMessageRequest struct {
+	Channel   string `json:"channel" binding:"required"`
+	MessageID int64  `json:"message_id" binding:"required"`
+	Reason    string `json:"reason"`
+}
+
+type MuteUserRequest struct {
+	Minutes int `json:"minutes" binding:"required,min=1"`
+}
+
+var chatService = chat.NewService(chat.DefaultConfig())
+
+// SetChatService remplace le service de chat
+func SetChatService(service *chat.Service) {
+	chatService = service
+}
+
+// GetGameChat retourne l'historique du chat d'une partie à ses joueurs et aux modérateurs
+func GetGameChat(c *gin.Context) {
+	g := game.GetGameManager().GetGame(c.Param("id"))
+	if g == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Game not found"})
+		return
+	}
+	username := c.GetString("username")
+	if !middleware.IsChatMember(username, isModerator(c), g.ID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "not a player of this game"})
+		return
+	}
+
+	messages := make([]chat.Message, 0)
+	for _, message := range chatService.History(g.ID) {
+		if !chatService.Ignores(username, message.From) {
+			messages = append(messages, message)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"channel": g.ID, "messages": messages})
+}
+
+// ListMutedUsers retourne les utilisateurs dont l'appelant masque les messages
+func ListMutedUsers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"muted": chatService.Ignored(c.GetString("username"))})
+}
+
+// MuteForMe masque à l'appelant les messages d'un utilisateur
+func MuteForMe(c *gin.Context) {
+	username := c.Param("username")
+	if username == c.GetString("username") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot mute yourself"})
+		return
+	}
+	chatService.Ignore(c.GetString("username"), username)
+	c.JSON(http.StatusOK, gin.H{"username": username, "muted": true})
+}
+
+// UnmuteForMe affiche de nouveau à l'appelant les messages d'un utilisateur
+func UnmuteForMe(c *gin.Context) {
+	username := c.Param("username")
+	chatService.Unignore(c.GetString("username"), username)
+	c.JSON(http.StatusOK, gin.H{"username": username, "muted": false})
+}
+
+// ListEmotes retourne le catalogue des emotes
+func ListEmotes(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"emotes": chat.Emotes})
+}
+
+// GetEmoteSettings indique si l'appelant masque toutes les emotes
+func GetEmoteSettings(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"muted": chatService.EmotesMuted(c.GetString("username"))})
+}
+
+// MuteEmotes masque toutes les emotes à l'appelant
+func MuteEmotes(c *gin.Context) {
+	chatService.MuteEmotes(c.GetString("username"), true)
+	c.JSON(http.StatusOK, gin.H{"muted": true})
+}
+
+// UnmuteEmotes affiche de nouveau les emotes à l'appelant
+func UnmuteEmotes(c *gin.Context) {
+	chatService.MuteEmotes(c.GetString("username"), false)
+	c.JSON(http.StatusOK, gin.H{"muted": false})
+}
+
+// ReportMessage signale un message du chat à la modération
+func ReportMessage(c *gin.Context) {
+	var req ReportMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// On ne signale que les messages d'un canal où l'on pourrait écrire
+	username := c.GetString("username")
+	if !middleware.IsChatMember(username, isModerator(c), req.Channel) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "not in this chat channel"})
+		return
+	}
+
+	err := chatService.Report(req.Channel, req.MessageID, username, req.Reason)
+	if errors.Is(err, chat.ErrMessageNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Message reported"})
+}
+
+// isModerator indique si l'utilisateur authentifié modère les parties
+func isModerator(c *gin.Context) bool {
+	return middleware.HasRole(c, middleware.RoleModerator) || middleware.HasRole(c, middleware.RoleAdmin)
+}
+
+// ListChatReports retourne les messages signalés
+func ListChatReports(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"reports": chatService.Reports()})
+}
+
+// MuteUser empêche un utilisateur d'écrire dans le chat pendant quelques minutes
+func MuteUser(c *gin.Context) {
+	var req MuteUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	username := c.Param("username")
+	until := time.Now().Add(time.Duration(req.Minutes) * time.Minute)
+	chatService.Mute(username, until)
+	c.JSON(http.StatusOK, gin.H{"username": username, "muted_until": until})
+}
+
+// UnmuteUser rend la parole à un utilisateur
+func UnmuteUser(c *gin.Context) {
+	username := c.Param("username")
+	chatService.Unmute(username)
+	c.JSON(http.StatusOK, gin.H{"username": username, "muted_until": nil})
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/becaraya/katana-api/api/middleware"
+	"github.com/becaraya/katana-api/internal/chat"
+)
+
+func TestReportMessageRequiresChannelMembership(t *testing.T) {
+	previous := chatService
+	SetChatService(chat.NewService(chat.DefaultConfig()))
+	t.Cleanup(func() { SetChatService(previous) })
+
+	router, _ := newTestRouter()
+	g := startedGame(t, "report")
+	message, err := chatService.Post(g.ID, "report-1", "hello")
+	if err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	report := ReportMessageRequest{Channel: g.ID, MessageID: message.ID, Reason: "rude"}
+
+	outsider := testToken(t, "report-outsider", middleware.RolePlayer)
+	if rec := perform(router, "/chat/reports", outsider, report); rec.Code != http.StatusForbidden {
+		t.Fatalf("report by an outsider: got %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	for _, token := range []string{
+		testToken(t, "report-2", middleware.RolePlayer),
+		testToken(t, "report-moderator", middleware.RolePlayer, middleware.RoleModerator),
+	} {
+		if rec := perform(router, "/chat/reports", token, report); rec.Code != http.StatusOK {
+			t.Fatalf("report by a member: got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+
+	// Le salon d'accueil est ouvert à tous
+	lobby, err := chatService.Post(middleware.LobbyRoom, "report-1", "hello")
+	if err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	if rec := perform(router, "/chat/reports", outsider, ReportMessageRequest{Channel: middleware.LobbyRoom, MessageID: lobby.ID}); rec.Code != http.StatusOK {
+		t.Fatalf("report in the lobby: got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -1,7 +1,9 @@
 package handler
 
 import (
-	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 
 	"github.com/becaraya/katana-api/api/middleware"
@@ -9,52 +11,111 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// ErrActingForOther est renvoyée quand un joueur tente d'agir au nom d'un autre
+var ErrActingForOther = errors.New("cannot act for another user")
+
+// ErrNotAtTable est renvoyée quand un utilisateur qui n'est ni l'hôte ni
+// assis à la table tente de lancer la partie
+var ErrNotAtTable = errors.New("only the host, a seated player or an admin can start the game")
+
+// JoinGameRequest et LeaveGameRequest n'acceptent un username différent de
+// celui du token que pour un administrateur
 type JoinGameRequest struct {
-	Username string `json:"username" binding:"required"`
+	Username string `json:"username"`
 }
 
 type LeaveGameRequest struct {
-	Username string `json:"username" binding:"required"`
+	Username string `json:"username"`
+}
+
+// AddBotRequest choisit la sorte de bot, le bot heuristique par défaut
+type AddBotRequest struct {
+	Kind string `json:"kind"`
+}
+
+// actingUser retourne l'utilisateur au nom duquel agir : celui du token, ou
+// celui demandé si l'appelant est administrateur
+func actingUser(c *gin.Context, requested string) (string, error) {
+	username := c.GetString("username")
+	if requested == "" || requested == username {
+		return username, nil
+	}
+	if middleware.HasRole(c, middleware.RoleAdmin) {
+		return requested, nil
+	}
+	return "", ErrActingForOther
+}
+
+// gameView retourne la partie telle que l'appelant la voit : sa vue pour un
+// joueur assis, un modérateur ou un administrateur, ou tant que la partie
+// attend des joueurs, la vue différée des spectateurs pour les autres. Les réponses ne sérialisent jamais la partie
+// elle-même, que les délais de jeu modifient depuis d'autres goroutines : la
+// vue est une copie prise sous son verrou.
+func gameView(c *gin.Context, g *game.Game) *game.GameView {
+	username := c.GetString("username")
+	if g.GetState() == game.GameStateWaiting || g.HasPlayer(username) || middleware.HasRole(c, middleware.RoleModerator) || middleware.HasRole(c, middleware.RoleAdmin) {
+		return g.View(username)
+	}
+	return g.SpectatorView()
+}
+
+// bindOptionalJSON lit un corps JSON facultatif
+func bindOptionalJSON(c *gin.Context, obj interface{}) error {
+	if err := c.ShouldBindJSON(obj); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
 }
 
 // JoinGame permet à un joueur de rejoindre la partie
 func JoinGame(c *gin.Context) {
 	var req JoinGameRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindOptionalJSON(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	username, err := actingUser(c, req.Username)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
 
 	gameManager := game.GetGameManager()
-	player, success := gameManager.JoinCurrentGame(req.Username)
-
-	if !success {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not join game"})
+	player, err := gameManager.JoinCurrentGame(username)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, game.ErrGameInProgress) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Broadcaster la mise à jour du jeu
+	// Abonner le joueur au salon de la partie et y diffuser la mise à jour
 	currentGame := gameManager.GetCurrentGame()
-	message, _ := json.Marshal(map[string]interface{}{
-		"type":    "game_update",
-		"game":    currentGame,
-		"players": currentGame.GetPlayers(),
-	})
-	middleware.BroadcastMessage(message)
+	middleware.SubscribeUser(username, currentGame.ID)
+	middleware.BroadcastGameUpdate(currentGame, game.EventPlayerJoined)
+	middleware.SendChatHistory(username, currentGame.ID)
 
+	view := gameView(c, currentGame)
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Successfully joined game",
-		"player":  player,
-		"game":    currentGame,
+		"player":  view.Players[player.Name],
+		"game":    view,
 	})
 }
 
 func LeaveGame(c *gin.Context) {
 	var req LeaveGameRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindOptionalJSON(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	username, err := actingUser(c, req.Username)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
 
 	gameManager := game.GetGameManager()
 	currentGame := gameManager.GetCurrentGame()
@@ -64,29 +125,102 @@ func LeaveGame(c *gin.Context) {
 		return
 	}
 
-	success := currentGame.RemovePlayer(req.Username)
-	if !success {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Player not in game"})
+	if currentGame.GetState() != game.GameStateWaiting {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot leave a started game"})
 		return
 	}
 
-	// Broadcaster la mise à jour du jeu
-	broadcastMessage := middleware.WSMessage{
-		Type: "game_update",
-		Data: map[string]interface{}{
-			"game":    currentGame,
-			"players": currentGame.GetPlayers(),
-			"event":   "player_left",
-			"message": req.Username + " a quitté la partie!",
-		},
-		From: req.Username,
+	if err := currentGame.RemovePlayer(username); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, game.ErrLogWrite) {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
 	}
-	middleware.BroadcastToAll(broadcastMessage)
 
+	// Diffuser la mise à jour au salon de la partie et au salon d'accueil
+	middleware.UnsubscribeUser(username, currentGame.ID)
+	middleware.BroadcastGameUpdate(currentGame, game.EventPlayerLeft)
+
+	view := gameView(c, currentGame)
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Successfully left game",
-		"game":    currentGame,
-		"players": currentGame.GetPlayers(),
+		"game":    view,
+		"players": view.Players,
+	})
+}
+
+// ListBotKinds retourne les sortes de bots que l'hôte peut installer
+func ListBotKinds(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"kinds": game.BotKinds()})
+}
+
+// AddBot installe un bot sur un siège libre de la partie en attente, à la demande de l'hôte
+func AddBot(c *gin.Context) {
+	var req AddBotRequest
+	if err := bindOptionalJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Kind == "" {
+		req.Kind = game.BotHeuristic
+	}
+
+	currentGame := game.GetGameManager().GetCurrentGame()
+	if currentGame == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No active game"})
+		return
+	}
+	if c.GetString("username") != currentGame.CreatedBy && !middleware.HasRole(c, middleware.RoleAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": game.ErrNotHost.Error()})
+		return
+	}
+
+	player, err := currentGame.AddBot(req.Kind)
+	if errors.Is(err, game.ErrUnknownBot) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "kinds": game.BotKinds()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	middleware.BroadcastGameUpdate(currentGame, game.EventPlayerJoined)
+
+	view := gameView(c, currentGame)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Bot added",
+		"player":  view.Players[player.Name],
+		"game":    view,
+	})
+}
+
+// RemoveBot retire un bot de la partie en attente, à la demande de l'hôte
+func RemoveBot(c *gin.Context) {
+	currentGame := game.GetGameManager().GetCurrentGame()
+	if currentGame == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No active game"})
+		return
+	}
+	if c.GetString("username") != currentGame.CreatedBy && !middleware.HasRole(c, middleware.RoleAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": game.ErrNotHost.Error()})
+		return
+	}
+
+	if err := currentGame.RemoveBot(c.Param("name")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	middleware.BroadcastGameUpdate(currentGame, game.EventPlayerLeft)
+
+	view := gameView(c, currentGame)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Bot removed",
+		"game":    view,
+		"players": view.Players,
 	})
 }
 
@@ -100,16 +234,26 @@ func GetGameState(c *gin.Context) {
 	if currentGame == nil {
 		c.JSON(http.StatusOK, gin.H{
 			"game":            nil,
-			"players":         make(map[string]*game.Player),
+			"players":         make(map[string]*game.PlayerView),
 			"connected_users": connectedUsers,
+			"presence":        middleware.GetPresences(connectedUsers),
 		})
 		return
 	}
 
+	// Présence des utilisateurs connectés et des joueurs de la partie
+	view := gameView(c, currentGame)
+	usernames := append([]string(nil), connectedUsers...)
+	for name := range view.Players {
+		usernames = append(usernames, name)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"game":            currentGame,
-		"players":         currentGame.GetPlayers(),
+		"game":            view,
+		"players":         view.Players,
 		"connected_users": connectedUsers,
+		"presence":        middleware.GetPresences(usernames),
+		"view":            view,
 	})
 }
 
@@ -122,31 +266,203 @@ func StartGame(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "No active game"})
 		return
 	}
+	username := c.GetString("username")
+	if username != currentGame.CreatedBy && !currentGame.HasPlayer(username) && !middleware.HasRole(c, middleware.RoleAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": ErrNotAtTable.Error()})
+		return
+	}
 
 	players := currentGame.GetPlayers()
-	if len(players) < 3 {
+	if len(players) < game.MinPlayers {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error":            "Minimum 3 players required to start the game",
+			"error":            fmt.Sprintf("Minimum %d players required to start the game", game.MinPlayers),
 			"current_players":  len(players),
-			"minimum_required": 3,
+			"minimum_required": game.MinPlayers,
 		})
 		return
 	}
 
-	if !currentGame.StartGame() {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot start game"})
+	if err := currentGame.StartGame(); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, game.ErrLogWrite) {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Broadcaster le démarrage du jeu
-	message, _ := json.Marshal(map[string]interface{}{
-		"type": "game_started",
-		"game": currentGame,
-	})
-	middleware.BroadcastMessage(message)
+	// Annoncer le démarrage au salon d'accueil, et diffuser aux joueurs le delta de leur vue
+	middleware.PublishToRooms(middleware.NewGameEnvelope(middleware.MessageGameStarted, currentGame, middleware.GameStartedPayload{
+		Game: currentGame.View(""),
+	}), middleware.LobbyRoom)
+	middleware.BroadcastGameUpdate(currentGame, game.EventGameStarted)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Game started successfully",
-		"game":    currentGame,
+		"game":    gameView(c, currentGame),
+	})
+}
+
+// PauseGame met la partie en pause si l'hôte le demande, sinon enregistre un vote
+func PauseGame(c *gin.Context) {
+	username := c.GetString("username")
+	currentGame := game.GetGameManager().GetCurrentGame()
+
+	if currentGame == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No active game"})
+		return
+	}
+
+	// Un administrateur peut mettre la partie en pause à la place de l'hôte
+	var err error
+	paused := true
+	if username == currentGame.CreatedBy || middleware.HasRole(c, middleware.RoleAdmin) {
+		err = currentGame.Pause(currentGame.CreatedBy)
+	} else {
+		paused, err = currentGame.VotePause(username)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	event := "pause_vote"
+	if paused {
+		event = "game_paused"
+	}
+	middleware.BroadcastGameUpdate(currentGame, event)
+
+	c.JSON(http.StatusOK, gin.H{
+		"paused": paused,
+		"game":   gameView(c, currentGame),
+	})
+}
+
+// ResumeGame reprend la partie si l'hôte le demande, sinon enregistre un vote
+func ResumeGame(c *gin.Context) {
+	username := c.GetString("username")
+	currentGame := game.GetGameManager().GetCurrentGame()
+
+	if currentGame == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No active game"})
+		return
+	}
+
+	var err error
+	resumed := true
+	if username == currentGame.CreatedBy || middleware.HasRole(c, middleware.RoleAdmin) {
+		err = currentGame.Resume(currentGame.CreatedBy)
+	} else {
+		resumed, err = currentGame.VoteResume(username)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	event := "resume_vote"
+	if resumed {
+		event = "game_resumed"
+	}
+	middleware.BroadcastGameUpdate(currentGame, event)
+
+	c.JSON(http.StatusOK, gin.H{
+		"resumed": resumed,
+		"game":    gameView(c, currentGame),
+	})
+}
+
+// PlayCommand applique une commande de jeu au nom du joueur authentifié
+func PlayCommand(c *gin.Context) {
+	var cmd game.Command
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// Une commande n'est jamais jouée au nom d'un autre joueur, même par un administrateur
+	if cmd.Player != "" && cmd.Player != c.GetString("username") {
+		c.JSON(http.StatusForbidden, gin.H{"error": ErrActingForOther.Error()})
+		return
+	}
+	cmd.Player = c.GetString("username")
+
+	currentGame := game.GetGameManager().GetCurrentGame()
+	if currentGame == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No active game"})
+		return
+	}
+
+	if err := currentGame.Apply(cmd); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, game.ErrLogWrite) {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	middleware.BroadcastGameUpdate(currentGame, "command")
+
+	view := gameView(c, currentGame)
+	c.JSON(http.StatusOK, gin.H{
+		"game": view,
+		"hand": view.Hand,
 	})
 }
+
+// GetHand retourne la main du joueur authentifié
+func GetHand(c *gin.Context) {
+	currentGame := game.GetGameManager().GetCurrentGame()
+	if currentGame == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No active game"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"hand": currentGame.Hand(c.GetString("username"))})
+}
+
+// SpectateGame retourne la vue publique, éventuellement différée, de la partie actuelle
+func SpectateGame(c *gin.Context) {
+	currentGame := game.GetGameManager().GetCurrentGame()
+	if currentGame == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No active game"})
+		return
+	}
+	if currentGame.HasPlayer(c.GetString("username")) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": game.ErrAlreadyPlayer.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"game": currentGame.SpectatorView()})
+}
+
+// ListArchivedGames retourne les parties archivées, sans leurs informations cachées
+func ListArchivedGames(c *gin.Context) {
+	archived := game.GetGameManager().GetArchive().List()
+
+	games := make([]gin.H, 0, len(archived))
+	for _, entry := range archived {
+		games = append(games, publicArchive(entry))
+	}
+	c.JSON(http.StatusOK, gin.H{"games": games})
+}
+
+// GetArchivedGame retourne une partie archivée, sans ses informations cachées
+func GetArchivedGame(c *gin.Context) {
+	entry, exists := game.GetGameManager().GetArchive().Get(c.Param("id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Game not found"})
+		return
+	}
+	c.JSON(http.StatusOK, publicArchive(entry))
+}
+
+func publicArchive(entry *game.ArchivedGame) gin.H {
+	return gin.H{
+		"id":          entry.ID,
+		"archived_at": entry.ArchivedAt,
+		"end_reason":  entry.EndReason,
+		"result":      entry.Result,
+		"game":        entry.View,
+	}
+}
@@ -0,0 +1,251 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/becaraya/katana-api/api/middleware"
+	"github.com/becaraya/katana-api/internal/bootstrap"
+	"github.com/becaraya/katana-api/internal/game"
+	"github.com/gin-gonic/gin"
+)
+
+const testSecret = "test_secret"
+
+var testEnv = &bootstrap.Env{
+	AccessTokenSecret:      testSecret,
+	AccessTokenExpiryHour:  1,
+	RefreshTokenSecret:     testSecret + "_refresh",
+	RefreshTokenExpiryHour: 1,
+}
+
+func newTestRouter() (*gin.Engine, *middleware.TokenStore) {
+	gin.SetMode(gin.TestMode)
+	tokens := middleware.NewTokenStore()
+	SetTokenStore(tokens)
+	router := gin.New()
+	router.POST("/login/guest", GuestLogin(testEnv))
+	protected := router.Group("")
+	protected.Use(middleware.JWTAuthMiddleware(testSecret, tokens))
+	protected.POST("/game/join", JoinGame)
+	protected.POST("/game/leave", LeaveGame)
+	protected.POST("/game/start", StartGame)
+	protected.POST("/game/command", PlayCommand)
+	protected.POST("/chat/reports", ReportMessage)
+	return router, tokens
+}
+
+func testToken(t *testing.T, username string, roles ...string) string {
+	t.Helper()
+	token, _, err := middleware.GenerateToken(username, false, roles, middleware.TokenKindAccess, testSecret, time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	return token
+}
+
+// removeOnCleanup retire des joueurs de la partie partagée à la fin du test
+func removeOnCleanup(t *testing.T, names ...string) {
+	t.Cleanup(func() {
+		if current := game.GetGameManager().GetCurrentGame(); current != nil {
+			for _, name := range names {
+				current.RemovePlayer(name)
+			}
+		}
+	})
+}
+
+func perform(router *gin.Engine, path string, token string, body interface{}) *httptest.ResponseRecorder {
+	var payload []byte
+	if body != nil {
+		payload, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(payload))
+	req.Header.Set("Authorization", token)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestJoinGameCannotJoinForAnotherUser(t *testing.T) {
+	router, _ := newTestRouter()
+	alice := testToken(t, "join-alice", middleware.RolePlayer)
+	removeOnCleanup(t, "join-alice", "join-bob")
+
+	rec := perform(router, "/game/join", alice, JoinGameRequest{Username: "join-bob"})
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("join as another user: got %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if game.GetGameManager().GetCurrentGame() != nil && game.GetGameManager().GetCurrentGame().HasPlayer("join-bob") {
+		t.Fatal("join-bob was added by join-alice")
+	}
+
+	rec = perform(router, "/game/join", alice, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("join as self: got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !game.GetGameManager().GetCurrentGame().HasPlayer("join-alice") {
+		t.Fatal("join-alice is not in the game")
+	}
+}
+
+// startedGame lance une nouvelle partie actuelle entre des joueurs nommés
+// prefix-1, prefix-2…, remplacée à la fin du test par une table en attente
+func startedGame(t *testing.T, prefix string) *game.Game {
+	t.Helper()
+	gameManager := game.GetGameManager()
+	started, err := gameManager.CreateGame(prefix + "-1")
+	if err != nil {
+		t.Fatalf("CreateGame: %v", err)
+	}
+	for i := 1; i <= game.MinPlayers; i++ {
+		if err := started.AddPlayer(game.NewPlayer(prefix+"-"+strconv.Itoa(i), 0)); err != nil {
+			t.Fatalf("AddPlayer: %v", err)
+		}
+	}
+	if err := started.StartGame(); err != nil {
+		t.Fatalf("game did not start: %v", err)
+	}
+	t.Cleanup(func() {
+		started.Abort(game.EndReasonModerator)
+		gameManager.CreateGame("test")
+	})
+	return started
+}
+
+func TestJoinStartedGameConflicts(t *testing.T) {
+	router, _ := newTestRouter()
+	started := startedGame(t, "started")
+	late := testToken(t, "started-late", middleware.RolePlayer)
+
+	rec := perform(router, "/game/join", late, nil)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("join a started game: got %d, want %d: %s", rec.Code, http.StatusConflict, rec.Body.String())
+	}
+	if started.HasPlayer("started-late") || len(started.GetPlayers()) != game.MinPlayers {
+		t.Fatal("a seat was added to the started game")
+	}
+}
+
+func TestStartGameRequiresSeatHostOrAdmin(t *testing.T) {
+	router, _ := newTestRouter()
+	alice := testToken(t, "start-alice", middleware.RolePlayer)
+	outsider := testToken(t, "start-outsider", middleware.RolePlayer)
+	removeOnCleanup(t, "start-alice")
+	perform(router, "/game/join", alice, nil)
+
+	rec := perform(router, "/game/start", outsider, nil)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("start by an outsider: got %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	// Un joueur assis ou un administrateur passe le contrôle et bute sur le nombre de joueurs
+	admin := testToken(t, "start-admin", middleware.RolePlayer, middleware.RoleAdmin)
+	for _, token := range []string{alice, admin} {
+		rec = perform(router, "/game/start", token, nil)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("start with too few players: got %d, want %d: %s", rec.Code, http.StatusBadRequest, rec.Body.String())
+		}
+	}
+	if game.GetGameManager().GetCurrentGame().GetState() != game.GameStateWaiting {
+		t.Fatal("the game started")
+	}
+}
+
+func TestLeaveGameCannotRemoveAnotherUser(t *testing.T) {
+	router, _ := newTestRouter()
+	alice := testToken(t, "leave-alice", middleware.RolePlayer)
+	bob := testToken(t, "leave-bob", middleware.RolePlayer)
+	removeOnCleanup(t, "leave-alice", "leave-bob")
+	perform(router, "/game/join", alice, nil)
+	perform(router, "/game/join", bob, nil)
+
+	rec := perform(router, "/game/leave", alice, LeaveGameRequest{Username: "leave-bob"})
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("leave for another user: got %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	current := game.GetGameManager().GetCurrentGame()
+	if !current.HasPlayer("leave-bob") {
+		t.Fatal("leave-bob was removed by leave-alice")
+	}
+
+	rec = perform(router, "/game/leave", alice, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("leave as self: got %d: %s", rec.Code, rec.Body.String())
+	}
+	if current.HasPlayer("leave-alice") || !current.HasPlayer("leave-bob") {
+		t.Fatal("leave as self removed the wrong player")
+	}
+}
+
+func TestAdminCanRemoveAnotherUser(t *testing.T) {
+	router, _ := newTestRouter()
+	bob := testToken(t, "kick-bob", middleware.RolePlayer)
+	admin := testToken(t, "kick-admin", middleware.RolePlayer, middleware.RoleAdmin)
+	removeOnCleanup(t, "kick-bob")
+	perform(router, "/game/join", bob, nil)
+
+	rec := perform(router, "/game/leave", admin, LeaveGameRequest{Username: "kick-bob"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("admin kick: got %d: %s", rec.Code, rec.Body.String())
+	}
+	if game.GetGameManager().GetCurrentGame().HasPlayer("kick-bob") {
+		t.Fatal("kick-bob is still in the game")
+	}
+}
+
+func TestPlayCommandCannotPlayForAnotherUser(t *testing.T) {
+	router, _ := newTestRouter()
+	for _, roles := range [][]string{{middleware.RolePlayer}, {middleware.RolePlayer, middleware.RoleAdmin}} {
+		token := testToken(t, "command-alice", roles...)
+		rec := perform(router, "/game/command", token, game.Command{Type: game.CommandEndTurn, Player: "command-bob"})
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("command for another user with roles %v: got %d, want %d", roles, rec.Code, http.StatusForbidden)
+		}
+	}
+}
+
+func TestRevokedTokenCannotAct(t *testing.T) {
+	router, tokens := newTestRouter()
+	token, claims, err := middleware.GenerateToken("revoked-alice", false, nil, middleware.TokenKindAccess, testSecret, time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	tokens.Revoke(claims.Info())
+
+	rec := perform(router, "/game/join", token, nil)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("join with revoked token: got %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestStartGameNeedsMinimumPlayers(t *testing.T) {
+	router, _ := newTestRouter()
+	token := testToken(t, "few-1", middleware.RolePlayer)
+	removeOnCleanup(t, "few-1")
+	if rec := perform(router, "/game/join", token, nil); rec.Code != http.StatusOK {
+		t.Fatalf("join: got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec := perform(router, "/game/start", token, nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("start alone: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	var body struct {
+		MinimumRequired int `json:"minimum_required"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.MinimumRequired != game.MinPlayers {
+		t.Fatalf("minimum_required is %d, want %d", body.MinimumRequired, game.MinPlayers)
+	}
+	if game.GetGameManager().GetCurrentGame().GetState() != game.GameStateWaiting {
+		t.Fatal("the game started without enough players")
+	}
+}
@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"github.com/becaraya/katana-api/internal/chat"
+	"github.com/becaraya/katana-api/internal/game"
+)
+
+// Service de chat partagé par le WebSocket et les routes de modération
+var chatService = chat.NewService(chat.DefaultConfig())
+
+// SetChatService remplace le service de chat
+func SetChatService(service *chat.Service) {
+	chatService = service
+}
+
+// IsChatMember indique si un utilisateur appartient à un canal : le salon
+// d'accueil, ou une partie où il est assis sans en avoir été exclu, ou qu'il modère
+func IsChatMember(username string, moderator bool, channel string) bool {
+	if channel == LobbyRoom {
+		return true
+	}
+	g := game.GetGameManager().GetGame(channel)
+	if g == nil {
+		return false
+	}
+	return moderator || g.HasPlayer(username) && !g.IsKicked(username)
+}
+
+// canChat indique si une connexion peut écrire dans un canal : elle doit en
+// être membre et suivre son salon sans être spectatrice
+func canChat(c *client, channel string) bool {
+	clientsMutex.RLock()
+	following := c.rooms[channel] && isPlayerClient(c) && c.stream == ""
+	clientsMutex.RUnlock()
+	return following && IsChatMember(c.username, c.hasRole(RoleModerator, RoleAdmin), channel)
+}
+
+// postChat publie un message de chat envoyé par une connexion
+func postChat(c *client, request ChatPostPayload) {
+	if !canChat(c, request.Channel) {
+		sendError(c, "not in this chat channel")
+		return
+	}
+	message, err := chatService.Post(request.Channel, c.username, request.Text)
+	if err != nil {
+		sendError(c, err.Error())
+		return
+	}
+	publishChat(message)
+}
+
+// publishChat diffuse un message aux joueurs du canal, sauf à ceux qui ont
+// masqué son auteur
+func publishChat(message chat.Message) {
+	envelope := NewEnvelope(MessageChat, message)
+	if message.Channel != LobbyRoom {
+		envelope.GameID = message.Channel
+	}
+	broadcast(envelope, func(c *client) bool {
+		return isPlayerClient(c) && c.rooms[message.Channel] && !chatService.Ignores(c.username, message.From)
+	})
+}
+
+// sendEmote diffuse à la table une emote envoyée par un joueur de la partie
+func sendEmote(c *client, request SendEmotePayload) {
+	g := game.GetGameManager().GetGame(request.GameID)
+	if g == nil || !g.HasPlayer(c.username) || !canChat(c, g.ID) {
+		sendError(c, "not a player of this game")
+		return
+	}
+	if request.EventSeq < 0 || request.EventSeq > g.LastSeq() {
+		sendError(c, "unknown event")
+		return
+	}
+	emote, err := chatService.SendEmote(c.username, request.Emote)
+	if err != nil {
+		sendError(c, err.Error())
+		return
+	}
+
+	envelope := NewGameEnvelope(MessageEmote, g, EmotePayload{
+		From:     c.username,
+		Emote:    emote.ID,
+		Label:    emote.Label,
+		EventSeq: request.EventSeq,
+	})
+	broadcast(envelope, func(viewer *client) bool {
+		return isPlayerClient(viewer) && viewer.rooms[g.ID] && chatService.ShowsEmote(viewer.username, c.username)
+	})
+}
+
+// sendChatHistory envoie à une connexion les derniers messages d'un canal
+func sendChatHistory(c *client, channel string) {
+	messages := chatService.Recent(channel)
+	visible := make([]chat.Message, 0, len(messages))
+	for _, message := range messages {
+		if !chatService.Ignores(c.username, message.From) {
+			visible = append(visible, message)
+		}
+	}
+
+	envelope := NewEnvelope(MessageChatHistory, ChatHistoryPayload{Channel: channel, Messages: visible})
+	if channel != LobbyRoom {
+		envelope.GameID = channel
+	}
+	sendToConnection(c, envelope)
+}
+
+// SendChatHistory envoie les derniers messages d'un canal aux connexions de
+// joueur d'un utilisateur qui viennent de le rejoindre
+func SendChatHistory(username string, channel string) {
+	clientsMutex.RLock()
+	var joined []*client
+	for c := range clients {
+		if c.username == username && isPlayerClient(c) && c.rooms[channel] {
+			joined = append(joined, c)
+		}
+	}
+	clientsMutex.RUnlock()
+
+	for _, c := range joined {
+		sendChatHistory(c, channel)
+	}
+}
+
+// DropGameChat supprime l'historique du chat d'une partie retirée
+func DropGameChat(g *game.Game) {
+	chatService.DropChannel(g.ID)
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/becaraya/katana-api/internal/chat"
+)
+
+func TestMutedEmotesAreNotDelivered(t *testing.T) {
+	previous := chatService
+	SetChatService(chat.NewService(chat.DefaultConfig()))
+	t.Cleanup(func() { SetChatService(previous) })
+
+	url := newTestServer(t, WebSocketConfig{})
+	g := startedGame(t, "emote")
+	sender := dial(t, url, "emote-1")
+	watcher := dial(t, url, "emote-2")
+	muted := dial(t, url, "emote-3")
+	chatService.MuteEmotes("emote-3", true)
+
+	send(t, sender, MessageSendEmote, SendEmotePayload{GameID: g.ID, Emote: "bow"})
+	var emote EmotePayload
+	if err := json.Unmarshal(readUntil(t, watcher, MessageEmote).Payload, &emote); err != nil {
+		t.Fatal(err)
+	}
+	if emote.From != "emote-1" || emote.Emote != "bow" {
+		t.Fatalf("got emote %+v", emote)
+	}
+
+	// Le chat, envoyé après l'emote, arrive seul à celui qui les masque
+	send(t, sender, MessagePostChat, ChatPostPayload{Channel: g.ID, Text: "bonjour"})
+	muted.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		var message receivedMessage
+		if err := muted.ReadJSON(&message); err != nil {
+			t.Fatalf("waiting for the chat message: %v", err)
+		}
+		if message.Type == MessageEmote {
+			t.Fatal("an emote reached a player who muted them")
+		}
+		if message.Type == MessageChat {
+			break
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"encoding/json"
+	"log"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/becaraya/katana-api/internal/game"
+)
+
+// PatchOperation est une opération JSON Patch (RFC 6902) sur la vue d'une
+// partie ; Value est ignorée par remove
+type PatchOperation struct {
+	Op    string      `json:"op" jsonschema:"enum=add,enum=remove,enum=replace"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// sentView est la dernière vue d'une partie envoyée à une connexion, sur
+// laquelle s'appliquera le prochain delta
+type sentView struct {
+	seq      int64
+	document interface{}
+}
+
+// viewFor retourne la vue d'une partie destinée à une connexion : la vue
+// publique, éventuellement différée, pour un spectateur, sinon celle de son utilisateur
+func viewFor(c *client, g *game.Game) *game.GameView {
+	clientsMutex.RLock()
+	spectating := c.spectating == g
+	clientsMutex.RUnlock()
+
+	if spectating {
+		return g.SpectatorView()
+	}
+	return g.View(c.username)
+}
+
+// sendSnapshot envoie la vue complète d'une partie à une connexion et la
+// retient comme base des deltas suivants
+func sendSnapshot(c *client, g *game.Game, messageType MessageType) {
+	c.viewsMutex.Lock()
+	defer c.viewsMutex.Unlock()
+
+	view := viewFor(c, g)
+	document, err := toDocument(view)
+	if err != nil {
+		log.Printf("Erreur lors de la sérialisation de la vue: %v", err)
+		return
+	}
+	c.views[g.ID] = &sentView{seq: view.Seq, document: document}
+
+	envelope := NewEnvelope(messageType, GameViewPayload{Game: view})
+	envelope.GameID = g.ID
+	envelope.Seq = view.Seq
+	sendToConnection(c, envelope)
+}
+
+// sendDelta envoie à une connexion les changements de la vue d'une partie
+// depuis la dernière vue qu'elle a reçue, ou la vue complète si elle n'en a
+// reçu aucune
+func sendDelta(c *client, g *game.Game, event string) {
+	c.viewsMutex.Lock()
+	sent := c.views[g.ID]
+	if sent == nil {
+		c.viewsMutex.Unlock()
+		sendSnapshot(c, g, MessageSnapshot)
+		return
+	}
+	defer c.viewsMutex.Unlock()
+
+	view := viewFor(c, g)
+	if view.Seq < sent.seq {
+		// Une diffusion plus récente est déjà passée
+		return
+	}
+	document, err := toDocument(view)
+	if err != nil {
+		log.Printf("Erreur lors de la sérialisation de la vue: %v", err)
+		return
+	}
+	operations := diffDocuments(sent.document, document, "", nil)
+	if len(operations) == 0 {
+		return
+	}
+
+	envelope := NewEnvelope(MessageGameDelta, GameDeltaPayload{
+		Event: event,
+		Base:  sent.seq,
+		Ops:   operations,
+	})
+	envelope.GameID = g.ID
+	envelope.Seq = view.Seq
+	c.views[g.ID] = &sentView{seq: view.Seq, document: document}
+	sendToConnection(c, envelope)
+}
+
+// forgetView oublie la dernière vue d'une partie envoyée à une connexion
+func forgetView(c *client, gameID string) {
+	c.viewsMutex.Lock()
+	defer c.viewsMutex.Unlock()
+	delete(c.views, gameID)
+}
+
+// toDocument convertit une vue en document JSON générique à comparer
+func toDocument(view *game.GameView) (interface{}, error) {
+	data, err := json.Marshal(view)
+	if err != nil {
+		return nil, err
+	}
+	var document interface{}
+	err = json.Unmarshal(data, &document)
+	return document, err
+}
+
+// diffDocuments ajoute aux opérations celles qui transforment before en after.
+// Les objets sont comparés clé par clé ; les tableaux et les valeurs qui
+// diffèrent sont remplacés en entier.
+func diffDocuments(before, after interface{}, path string, operations []PatchOperation) []PatchOperation {
+	beforeObject, beforeIsObject := before.(map[string]interface{})
+	afterObject, afterIsObject := after.(map[string]interface{})
+	if !beforeIsObject || !afterIsObject {
+		if !reflect.DeepEqual(before, after) {
+			operations = append(operations, PatchOperation{Op: "replace", Path: path, Value: after})
+		}
+		return operations
+	}
+
+	keys := make([]string, 0, len(beforeObject)+len(afterObject))
+	for key := range beforeObject {
+		keys = append(keys, key)
+	}
+	for key := range afterObject {
+		if _, exists := beforeObject[key]; !exists {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		keyPath := path + "/" + escapePointer(key)
+		beforeValue, inBefore := beforeObject[key]
+		afterValue, inAfter := afterObject[key]
+		switch {
+		case !inAfter:
+			operations = append(operations, PatchOperation{Op: "remove", Path: keyPath})
+		case !inBefore:
+			operations = append(operations, PatchOperation{Op: "add", Path: keyPath, Value: afterValue})
+		default:
+			operations = diffDocuments(beforeValue, afterValue, keyPath, operations)
+		}
+	}
+	return operations
+}
+
+// escapePointer échappe une clé pour un JSON Pointer (RFC 6901)
+func escapePointer(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/becaraya/katana-api/internal/game"
+)
+
+// decodeJSON relit un document JSON sous sa forme générique
+func decodeJSON(t *testing.T, data string) interface{} {
+	t.Helper()
+	var document interface{}
+	if err := json.Unmarshal([]byte(data), &document); err != nil {
+		t.Fatal(err)
+	}
+	return document
+}
+
+// applyPatch applique des opérations, relues comme un client les reçoit, à une
+// copie du document ; les tableaux étant remplacés en entier, les chemins ne
+// traversent que des objets
+func applyPatch(t *testing.T, document interface{}, operations []PatchOperation) interface{} {
+	t.Helper()
+	data, err := json.Marshal(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	document = decodeJSON(t, string(data))
+	if data, err = json.Marshal(operations); err != nil {
+		t.Fatal(err)
+	}
+	var received []PatchOperation
+	if err := json.Unmarshal(data, &received); err != nil {
+		t.Fatal(err)
+	}
+
+	unescape := strings.NewReplacer("~1", "/", "~0", "~")
+	for _, operation := range received {
+		if operation.Path == "" {
+			document = operation.Value
+			continue
+		}
+		tokens := strings.Split(operation.Path[1:], "/")
+		parent, _ := document.(map[string]interface{})
+		for _, token := range tokens[:len(tokens)-1] {
+			parent, _ = parent[unescape.Replace(token)].(map[string]interface{})
+		}
+		if parent == nil {
+			t.Fatalf("%s %s does not point into an object", operation.Op, operation.Path)
+		}
+		key := unescape.Replace(tokens[len(tokens)-1])
+		switch operation.Op {
+		case "add", "replace":
+			parent[key] = operation.Value
+		case "remove":
+			delete(parent, key)
+		default:
+			t.Fatalf("unknown operation %q", operation.Op)
+		}
+	}
+	return document
+}
+
+func TestDiffDocumentsRoundTrip(t *testing.T) {
+	cases := []struct {
+		name, before, after string
+	}{
+		{"unchanged", `{"a":1,"b":[1,2]}`, `{"a":1,"b":[1,2]}`},
+		{"nested value", `{"turn":{"player":"p1","phase":"PLAY"}}`, `{"turn":{"player":"p2","phase":"PLAY"}}`},
+		{"added and removed keys", `{"a":1,"paused_at":"x"}`, `{"a":1,"result":{"winners":["p1"]}}`},
+		{"array grows and shrinks", `{"hand":[{"id":1},{"id":2}],"in_play":[3]}`, `{"hand":[{"id":2},{"id":5},{"id":6}],"in_play":[]}`},
+		{"array removed", `{"players":{"p1":{"in_play":[1]}}}`, `{"players":{"p1":{}}}`},
+		{"escaped keys", `{"a/b":1,"c~d":{"e":1}}`, `{"a/b":2,"c~d":{"e":2}}`},
+		{"value changes type", `{"a":{"b":1},"c":[1]}`, `{"a":[1],"c":{"d":null}}`},
+		{"root replaced", `{"a":1}`, `[1,2]`},
+	}
+	for _, c := range cases {
+		before, after := decodeJSON(t, c.before), decodeJSON(t, c.after)
+		operations := diffDocuments(before, after, "", nil)
+		if got := applyPatch(t, before, operations); !reflect.DeepEqual(got, after) {
+			t.Fatalf("%s: patch %+v gives %v, want %v", c.name, operations, got, after)
+		}
+		if c.before == c.after && len(operations) != 0 {
+			t.Fatalf("%s: equal documents give the patch %+v", c.name, operations)
+		}
+	}
+}
+
+func TestDiffViewsRoundTrip(t *testing.T) {
+	g := game.NewGame("p1")
+	g.Rules = game.Ruleset{}
+	for _, name := range []string{"p1", "p2", "p3", "p4"} {
+		g.AddPlayer(game.NewPlayer(name, 0))
+	}
+
+	previous := make(map[string]interface{})
+	for step := 0; step < 40 && g.GetState() != game.GameStateEnded; step++ {
+		for _, viewer := range []string{"", "p1", "p2"} {
+			document, err := toDocument(g.View(viewer))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if before, seen := previous[viewer]; seen {
+				if got := applyPatch(t, before, diffDocuments(before, document, "", nil)); !reflect.DeepEqual(got, document) {
+					t.Fatalf("step %d: the patch of %q's view does not give the new view", step, viewer)
+				}
+			}
+			previous[viewer] = document
+		}
+
+		if step == 0 {
+			if err := g.StartGame(); err != nil {
+				t.Fatalf("StartGame: %v", err)
+			}
+			continue
+		}
+		// L'action par défaut suffit à faire évoluer mains, tour et défausse
+		waiting := g.WaitingOn()
+		cmd := game.Command{Type: game.CommandEndTurn, Player: waiting}
+		if g.PendingReaction() != nil {
+			cmd.Type = game.CommandRespond
+		} else if g.View("").Turn.Phase == game.PhaseDiscard {
+			cmd.Type = game.CommandDiscard
+			hand := g.Hand(waiting)
+			for _, card := range hand[:len(hand)-game.HandLimit] {
+				cmd.Cards = append(cmd.Cards, card.ID)
+			}
+		}
+		if err := g.Apply(cmd); err != nil {
+			t.Fatalf("step %d: apply %+v: %v", step, cmd, err)
+		}
+	}
+}
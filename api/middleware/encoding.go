@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/gorilla/websocket"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Format est l'encodage des messages d'une connexion, négocié avec la version
+// du protocole par le sous-protocole WebSocket, par exemple katana.v1.msgpack
+type Format string
+
+const (
+	FormatJSON    Format = "json"
+	FormatMsgPack Format = "msgpack"
+
+	// FormatSSE encode un message en événement Server-Sent Events, dont l'id
+	// est le numéro d'événement de la partie
+	FormatSSE Format = "sse"
+)
+
+// Formats liste les encodages acceptés à la connexion WebSocket
+var Formats = []Format{FormatJSON, FormatMsgPack}
+
+// marshal encode un message ; MessagePack reprend les noms des champs JSON
+func (f Format) marshal(message Envelope) ([]byte, error) {
+	switch f {
+	case FormatMsgPack:
+		var buffer bytes.Buffer
+		encoder := msgpack.NewEncoder(&buffer)
+		encoder.SetCustomStructTag("json")
+		encoder.UseCompactInts(true)
+		if err := encoder.Encode(message); err != nil {
+			return nil, err
+		}
+		return buffer.Bytes(), nil
+
+	case FormatSSE:
+		data, err := json.Marshal(message)
+		if err != nil {
+			return nil, err
+		}
+		var buffer bytes.Buffer
+		if message.Seq > 0 {
+			fmt.Fprintf(&buffer, "id: %d\n", message.Seq)
+		}
+		fmt.Fprintf(&buffer, "event: %s\ndata: %s\n\n", message.Type, data)
+		return buffer.Bytes(), nil
+
+	default:
+		return json.Marshal(message)
+	}
+}
+
+// frameType retourne le type de trame WebSocket des messages de ce format
+func (f Format) frameType() int {
+	if f == FormatMsgPack {
+		return websocket.BinaryMessage
+	}
+	return websocket.TextMessage
+}
+
+// decodeClientMessage décode un message du client. Un message MessagePack
+// est ramené à l'enveloppe JSON, dont le payload est décodé selon son type.
+func decodeClientMessage(f Format, data []byte) (ClientEnvelope, error) {
+	var message ClientEnvelope
+	if f != FormatMsgPack {
+		err := json.Unmarshal(data, &message)
+		return message, err
+	}
+
+	var decoded struct {
+		Version int         `json:"v"`
+		Type    MessageType `json:"type"`
+		Payload interface{} `json:"payload"`
+	}
+	decoder := msgpack.NewDecoder(bytes.NewReader(data))
+	decoder.SetCustomStructTag("json")
+	if err := decoder.Decode(&decoded); err != nil {
+		return message, err
+	}
+	message.Version = decoded.Version
+	message.Type = decoded.Type
+	if decoded.Payload != nil {
+		payload, err := json.Marshal(decoded.Payload)
+		if err != nil {
+			return message, err
+		}
+		message.Payload = payload
+	}
+	return message, nil
+}
+
+// encodedMessage sérialise un message diffusé au plus une fois par format,
+// quel que soit le nombre de connexions qui le reçoivent. Il n'est utilisé
+// que par la goroutine qui diffuse.
+type encodedMessage struct {
+	message Envelope
+	encoded map[Format][]byte
+}
+
+func newEncodedMessage(message Envelope) *encodedMessage {
+	return &encodedMessage{message: message, encoded: make(map[Format][]byte, len(Formats))}
+}
+
+// bytes retourne le message encodé dans un format, ou nil s'il ne peut pas l'être
+func (m *encodedMessage) bytes(f Format) []byte {
+	if data, ok := m.encoded[f]; ok {
+		return data
+	}
+	data, err := f.marshal(m.message)
+	if err != nil {
+		log.Printf("Erreur lors de la sérialisation du message en %s: %v", f, err)
+	}
+	m.encoded[f] = data
+	return data
+}
@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// FuzzDecodeClientMessage décode des trames quelconques comme celles lues sur
+// une connexion WebSocket, puis leur payload selon leur type : un message mal
+// formé doit être refusé sans faire paniquer le serveur
+func FuzzDecodeClientMessage(f *testing.F) {
+	f.Add([]byte(`{"v":1,"type":"subscribe","payload":{"game_id":"20240101120000"}}`), false)
+	f.Add([]byte(`{"type":"resume","payload":{"game_id":"x","last_seq":-1}}`), false)
+	f.Add([]byte(`{"type":"emote","payload":{"game_id":"x","emote":"bow","event_seq":1e99}}`), false)
+	f.Add([]byte(`{"type":"chat","payload":null}`), false)
+	f.Add([]byte(`{"type":`), false)
+	for _, message := range []map[string]interface{}{
+		{"v": 1, "type": "spectate", "payload": map[string]interface{}{"game_id": "x"}},
+		{"type": "presence", "payload": []interface{}{1, "a", nil}},
+		{"type": 42, "payload": "x"},
+	} {
+		data, err := msgpack.Marshal(message)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data, true)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte, binary bool) {
+		format := FormatJSON
+		if binary {
+			format = FormatMsgPack
+		}
+		message, err := decodeClientMessage(format, data)
+		if err != nil {
+			return
+		}
+		if payload, known := ClientMessages[message.Type]; known {
+			decodePayload(message, reflect.New(reflect.TypeOf(payload)).Interface())
+		}
+	})
+}
+
+func TestNegotiateProtocol(t *testing.T) {
+	cases := []struct {
+		name        string
+		query       string
+		subprotocol string
+		want        connectionProtocol
+		wantErr     bool
+	}{
+		{name: "nothing requested", want: connectionProtocol{version: 1, format: FormatJSON}},
+		{name: "version parameter", query: "v=1", want: connectionProtocol{version: 1, format: FormatJSON}},
+		{name: "unknown version parameter", query: "v=2", wantErr: true},
+		{name: "malformed version parameter", query: "v=one", wantErr: true},
+		{name: "subprotocol without format", subprotocol: "katana.v1", want: connectionProtocol{version: 1, format: FormatJSON, subprotocol: "katana.v1"}},
+		{name: "msgpack subprotocol", subprotocol: "katana.v1.msgpack", want: connectionProtocol{version: 1, format: FormatMsgPack, subprotocol: "katana.v1.msgpack"}},
+		{name: "first supported subprotocol", subprotocol: "graphql-ws, katana.v9, katana.v1.msgpack, katana.v1", want: connectionProtocol{version: 1, format: FormatMsgPack, subprotocol: "katana.v1.msgpack"}},
+		{name: "foreign subprotocols fall back to the parameter", query: "v=1", subprotocol: "graphql-ws", want: connectionProtocol{version: 1, format: FormatJSON}},
+		{name: "unknown format", subprotocol: "katana.v1.xml", wantErr: true},
+		{name: "announced subprotocols win over the parameter", query: "v=1", subprotocol: "katana.v9", wantErr: true},
+	}
+	for _, c := range cases {
+		request := httptest.NewRequest(http.MethodGet, "/ws?"+c.query, nil)
+		if c.subprotocol != "" {
+			request.Header.Set("Sec-WebSocket-Protocol", c.subprotocol)
+		}
+		got, err := negotiateProtocol(request)
+		if c.wantErr {
+			if err != ErrUnsupportedVersion {
+				t.Fatalf("%s: got %+v, %v, want %v", c.name, got, err, ErrUnsupportedVersion)
+			}
+			continue
+		}
+		if err != nil || got != c.want {
+			t.Fatalf("%s: got %+v, %v, want %+v", c.name, got, err, c.want)
+		}
+	}
+}
+
+func TestMsgPackEnvelopeRoundTrip(t *testing.T) {
+	sent := NewEnvelope(MessageError, ErrorPayload{Message: "not your turn"})
+	sent.GameID = "20260101120000"
+	sent.Seq = 42
+	data, err := FormatMsgPack.marshal(sent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var received struct {
+		Version   int          `json:"v"`
+		Type      MessageType  `json:"type"`
+		GameID    string       `json:"game_id"`
+		Seq       int64        `json:"seq"`
+		Timestamp time.Time    `json:"ts"`
+		Payload   ErrorPayload `json:"payload"`
+	}
+	decoder := msgpack.NewDecoder(bytes.NewReader(data))
+	decoder.SetCustomStructTag("json")
+	if err := decoder.Decode(&received); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if received.Version != sent.Version || received.Type != sent.Type || received.GameID != sent.GameID ||
+		received.Seq != sent.Seq || !received.Timestamp.Equal(sent.Timestamp) || received.Payload != sent.Payload {
+		t.Fatalf("got %+v, want %+v", received, sent)
+	}
+
+	// Un message du client en MessagePack se décode comme son équivalent JSON
+	client, err := msgpack.Marshal(map[string]interface{}{
+		"v": 1, "type": "resume", "payload": map[string]interface{}{"game_id": "x", "last_seq": 7},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromMsgPack, err := decodeClientMessage(FormatMsgPack, client)
+	if err != nil {
+		t.Fatalf("decode client message: %v", err)
+	}
+	fromJSON, err := decodeClientMessage(FormatJSON, []byte(`{"v":1,"type":"resume","payload":{"game_id":"x","last_seq":7}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resumeMsgPack, resumeJSON ResumePayload
+	if !decodePayload(fromMsgPack, &resumeMsgPack) || !decodePayload(fromJSON, &resumeJSON) ||
+		fromMsgPack.Type != fromJSON.Type || fromMsgPack.Version != fromJSON.Version || resumeMsgPack != resumeJSON {
+		t.Fatalf("msgpack message %+v differs from its JSON equivalent %+v", fromMsgPack, fromJSON)
+	}
+}
+
+func TestWebSocketNegotiation(t *testing.T) {
+	url := newTestServer(t, WebSocketConfig{})
+	token, _, err := GenerateToken("negotiate", false, nil, TokenKindAccess, testSecret, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Une version inconnue est refusée avant l'upgrade
+	dialer := websocket.Dialer{Subprotocols: []string{"katana.v9"}}
+	if _, response, err := dialer.Dial(url+"?token="+token, nil); err == nil || response == nil || response.StatusCode != http.StatusBadRequest {
+		t.Fatalf("dial with an unknown version: got %v, want %d", err, http.StatusBadRequest)
+	}
+
+	// Le format négocié est annoncé et utilisé dès le message hello
+	dialer = websocket.Dialer{Subprotocols: []string{"katana.v1.msgpack"}}
+	conn, _, err := dialer.Dial(url+"?token="+token, nil)
+	if err != nil {
+		t.Fatalf("dial with msgpack: %v", err)
+	}
+	defer conn.Close()
+	if conn.Subprotocol() != "katana.v1.msgpack" {
+		t.Fatalf("server chose subprotocol %q", conn.Subprotocol())
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	frameType, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hello struct {
+		Type    MessageType  `json:"type"`
+		Payload HelloPayload `json:"payload"`
+	}
+	decoder := msgpack.NewDecoder(bytes.NewReader(data))
+	decoder.SetCustomStructTag("json")
+	if frameType != websocket.BinaryMessage || decoder.Decode(&hello) != nil ||
+		hello.Type != MessageHello || hello.Payload.Format != FormatMsgPack || hello.Payload.Username != "negotiate" {
+		t.Fatalf("first frame is not a msgpack hello: type %d, %+v", frameType, hello)
+	}
+}
@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/becaraya/katana-api/internal/game"
+	"github.com/gin-gonic/gin"
+)
+
+// ssePing est le commentaire envoyé régulièrement pour garder le flux ouvert
+// à travers les proxys
+var ssePing = []byte(": ping\n\n")
+
+// EventStreamHandler diffuse en Server-Sent Events les messages d'une partie
+// aux clients en lecture seule qui ne peuvent pas garder de WebSocket ouverte.
+// Il s'utilise derrière JWTAuthMiddleware. Comme pour subscribe, les joueurs et
+// les modérateurs suivent la partie, les autres la regardent en spectateurs.
+// Chaque événement porte en id le numéro d'événement de la partie : à la
+// reconnexion, les événements postérieurs à Last-Event-ID sont rejoués avant
+// la vue complète.
+func EventStreamHandler(config WebSocketConfig) gin.HandlerFunc {
+	config = config.withDefaults()
+	return func(c *gin.Context) {
+		claims := c.MustGet("claims").(*Claims)
+		g := game.GetGameManager().GetGame(c.Param("id"))
+		if g == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Game not found"})
+			return
+		}
+
+		stream := newClient(nil, claims, connectionProtocol{version: ProtocolVersion, format: FormatSSE}, config)
+		stream.stream = g.ID
+		stream.rooms = map[string]bool{}
+		defer stream.close()
+
+		following := g.HasPlayer(claims.Username) || stream.hasRole(RoleModerator, RoleAdmin)
+		if following {
+			stream.rooms[g.ID] = true
+		} else {
+			if err := g.AddSpectator(claims.Username); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			stream.spectating = g
+		}
+
+		header := c.Writer.Header()
+		header.Set("Content-Type", "text/event-stream")
+		header.Set("Cache-Control", "no-cache")
+		header.Set("Connection", "keep-alive")
+		header.Set("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+
+		registerClient(stream)
+		defer func() {
+			stopSpectating(stream)
+			unregisterClient(stream)
+		}()
+
+		// Rejouer les événements manqués aux joueurs, puis envoyer la vue complète
+		if lastSeq, ok := lastEventID(c); ok && following {
+			if events, ok := g.EventsSince(lastSeq); ok {
+				sendToConnection(stream, NewGameEnvelope(MessageReplay, g, ReplayPayload{
+					Events: events,
+					Hand:   g.Hand(claims.Username),
+				}))
+			}
+		}
+		if following {
+			sendSnapshot(stream, g, MessageSnapshot)
+			sendChatHistory(stream, g.ID)
+		} else {
+			sendSnapshot(stream, g, MessageSpectatorUpdate)
+			BroadcastGameUpdate(g, "spectator_joined")
+		}
+
+		go watchToken(stream, claims, config.Tokens)
+		stream.streamPump(c.Request.Context(), c.Writer)
+	}
+}
+
+// lastEventID retourne le numéro du dernier événement reçu par le client,
+// dans l'en-tête Last-Event-ID ou le paramètre last_event_id
+func lastEventID(c *gin.Context) (int64, bool) {
+	value := c.GetHeader("Last-Event-ID")
+	if value == "" {
+		value = c.Query("last_event_id")
+	}
+	seq, err := strconv.ParseInt(value, 10, 64)
+	return seq, err == nil && seq >= 0
+}
+
+// streamPump écrit les messages de la file et les pings sur un flux SSE
+// jusqu'à sa fermeture par le client ou par le serveur
+func (c *client) streamPump(ctx context.Context, w gin.ResponseWriter) {
+	controller := http.NewResponseController(w)
+	ticker := time.NewTicker(c.config.PingInterval)
+	defer ticker.Stop()
+
+	write := func(data []byte) bool {
+		controller.SetWriteDeadline(time.Now().Add(c.config.WriteWait))
+		if _, err := w.Write(data); err != nil {
+			return false
+		}
+		return controller.Flush() == nil
+	}
+
+	if !write(ssePing) {
+		return
+	}
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ctx.Done():
+			return
+		case message := <-c.send:
+			if !write(message) {
+				return
+			}
+		case <-ticker.C:
+			if !write(ssePing) {
+				return
+			}
+		}
+	}
+}
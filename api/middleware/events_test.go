@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sseEvent est un événement lu sur un flux Server-Sent Events
+type sseEvent struct {
+	id      string
+	name    string
+	message receivedMessage
+}
+
+// openStream ouvre le flux d'une partie au nom de username, à partir de lastEventID s'il est donné
+func openStream(t *testing.T, server string, gameID string, username string, lastEventID string) *bufio.Reader {
+	t.Helper()
+	token, _, err := GenerateToken(username, false, nil, TokenKindAccess, testSecret, time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	request, err := http.NewRequest(http.MethodGet, server+"/games/"+gameID+"/events", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set("Authorization", token)
+	if lastEventID != "" {
+		request.Header.Set("Last-Event-ID", lastEventID)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("open stream as %s: %v", username, err)
+	}
+	t.Cleanup(func() { response.Body.Close() })
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("open stream as %s: status %d", username, response.StatusCode)
+	}
+	return bufio.NewReader(response.Body)
+}
+
+// readEvent lit le prochain événement du flux en ignorant les pings
+func readEvent(t *testing.T, stream *bufio.Reader) sseEvent {
+	t.Helper()
+	var event sseEvent
+	for {
+		line, err := stream.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading the stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && event.name != "":
+			return event
+		case strings.HasPrefix(line, "id: "):
+			event.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event.message); err != nil {
+				t.Fatalf("event data: %v", err)
+			}
+		}
+	}
+}
+
+func TestEventStreamReplaysFromLastEventID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tokens := NewTokenStore()
+	router := gin.New()
+	router.GET("/games/:id/events", JWTAuthMiddleware(testSecret, tokens),
+		EventStreamHandler(WebSocketConfig{TokenSecret: testSecret, Tokens: tokens}))
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	g := startedGame(t, "stream")
+	lastSeq := g.LastSeq()
+	if !g.Kick("stream-3") {
+		t.Fatal("kick failed")
+	}
+
+	// Un joueur reçoit les événements manqués puis la vue complète
+	stream := openStream(t, server.URL, g.ID, "stream-1", strconv.FormatInt(lastSeq, 10))
+	replay := readEvent(t, stream)
+	if replay.name != string(MessageReplay) {
+		t.Fatalf("first event is %q, want a replay", replay.name)
+	}
+	var payload ReplayPayload
+	if err := json.Unmarshal(replay.message.Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if len(payload.Events) == 0 {
+		t.Fatal("replay is empty")
+	}
+	for i, event := range payload.Events {
+		if event.Seq != lastSeq+int64(i)+1 {
+			t.Fatalf("replay is not contiguous from seq %d: %+v", lastSeq, payload.Events)
+		}
+	}
+	snapshot := readEvent(t, stream)
+	if snapshot.name != string(MessageSnapshot) || snapshot.id != strconv.FormatInt(g.LastSeq(), 10) {
+		t.Fatalf("got %q with id %q, want a snapshot at seq %d", snapshot.name, snapshot.id, g.LastSeq())
+	}
+
+	// Un spectateur ne reçoit pas de rejeu, seulement sa vue
+	watched := openStream(t, server.URL, g.ID, "stream-spectator", strconv.FormatInt(lastSeq, 10))
+	if first := readEvent(t, watched); first.name != string(MessageSpectatorUpdate) {
+		t.Fatalf("spectator first event is %q, want a spectator update", first.name)
+	}
+}
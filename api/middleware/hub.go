@@ -0,0 +1,289 @@
+package middleware
+
+import (
+	"log"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/becaraya/katana-api/internal/game"
+	"github.com/gorilla/websocket"
+)
+
+// LobbyRoom est le salon que rejoint chaque connexion, où sont diffusées les
+// mises à jour des parties en attente de joueurs
+const LobbyRoom = "lobby"
+
+// sendQueueSize est le nombre de messages en attente au-delà duquel une
+// connexion trop lente est fermée
+const sendQueueSize = 256
+
+// client représente une connexion WebSocket ou un flux SSE. Seule sa
+// goroutine d'écriture écrit sur la connexion ; les autres goroutines passent
+// par sa file d'envoi.
+type client struct {
+	conn     *websocket.Conn
+	username string
+	roles    []string
+	version  int
+	format   Format
+	config   WebSocketConfig
+	send     chan []byte
+	done     chan struct{}
+	once     sync.Once
+
+	// stream est la partie suivie par un flux SSE, vide pour une connexion
+	// WebSocket ; un flux n'a pas de conn et ne compte pas dans la présence
+	stream string
+
+	// lastActive est l'heure, en nanosecondes, du dernier message reçu du client
+	lastActive atomic.Int64
+
+	// rooms liste les salons suivis, spectating la partie regardée par un
+	// spectateur, et away indique un client absent ; tous sont protégés par clientsMutex
+	rooms      map[string]bool
+	spectating *game.Game
+	away       bool
+
+	// views garde, par partie, la dernière vue envoyée sur laquelle
+	// s'appliquent les deltas ; il est protégé par viewsMutex
+	views      map[string]*sentView
+	viewsMutex sync.Mutex
+}
+
+var (
+	// Connexions WebSocket actives
+	clients      = make(map[*client]bool)
+	clientsMutex sync.RWMutex
+)
+
+func newClient(conn *websocket.Conn, claims *Claims, protocol connectionProtocol, config WebSocketConfig) *client {
+	c := &client{
+		conn:     conn,
+		username: claims.Username,
+		roles:    claims.Roles,
+		version:  protocol.version,
+		format:   protocol.format,
+		config:   config,
+		send:     make(chan []byte, sendQueueSize),
+		done:     make(chan struct{}),
+		rooms:    map[string]bool{LobbyRoom: true},
+		views:    make(map[string]*sentView),
+	}
+	c.touch()
+	return c
+}
+
+// touch note une activité du client
+func (c *client) touch() {
+	c.lastActive.Store(time.Now().UnixNano())
+}
+
+// idleFor retourne le temps écoulé depuis le dernier message du client
+func (c *client) idleFor() time.Duration {
+	return time.Since(time.Unix(0, c.lastActive.Load()))
+}
+
+// writePump envoie les messages de la file et les pings jusqu'à la fermeture
+// de la connexion, et marque le client absent quand il reste inactif
+func (c *client) writePump() {
+	ticker := time.NewTicker(c.config.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case message := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(c.config.WriteWait))
+			if err := c.conn.WriteMessage(c.format.frameType(), message); err != nil {
+				log.Printf("Erreur lors de l'envoi du message WebSocket: %v", err)
+				c.close()
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(c.config.WriteWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.close()
+				return
+			}
+			if c.config.AwayAfter > 0 && c.idleFor() >= c.config.AwayAfter {
+				setAway(c, true)
+			}
+		}
+	}
+}
+
+// enqueue ajoute un message à la file sans bloquer, et déconnecte le client
+// si sa file est pleine
+func (c *client) enqueue(message []byte) {
+	select {
+	case <-c.done:
+	case c.send <- message:
+	default:
+		log.Printf("Connexion WebSocket de %s trop lente, fermeture", c.username)
+		c.close()
+	}
+}
+
+// close ferme la connexion une seule fois ; la boucle de lecture se charge du nettoyage
+func (c *client) close() {
+	c.once.Do(func() {
+		close(c.done)
+		if c.conn != nil {
+			c.conn.Close()
+		}
+	})
+}
+
+func registerClient(c *client) {
+	clientsMutex.Lock()
+	defer clientsMutex.Unlock()
+	clients[c] = true
+}
+
+func unregisterClient(c *client) {
+	clientsMutex.Lock()
+	defer clientsMutex.Unlock()
+	delete(clients, c)
+}
+
+// broadcast met un message dans la file de chaque client accepté par match,
+// encodé une seule fois par format
+func broadcast(message Envelope, match func(c *client) bool) {
+	encoded := newEncodedMessage(message)
+
+	clientsMutex.RLock()
+	defer clientsMutex.RUnlock()
+
+	for c := range clients {
+		if !match(c) || !c.accepts(message) {
+			continue
+		}
+		if data := encoded.bytes(c.format); data != nil {
+			c.enqueue(data)
+		}
+	}
+}
+
+// accepts indique si un client reçoit un message : un flux SSE ne reçoit
+// que les messages de sa partie et ceux qui ne concernent aucune partie
+func (c *client) accepts(message Envelope) bool {
+	return c.stream == "" || message.GameID == "" || message.GameID == c.stream
+}
+
+// isPlayerClient indique si un client reçoit les diffusions destinées aux joueurs
+func isPlayerClient(c *client) bool {
+	return c.spectating == nil
+}
+
+// BroadcastToAll diffuse un message à toutes les connexions actives
+func BroadcastToAll(message Envelope) {
+	broadcast(message, isPlayerClient)
+}
+
+// PublishToRooms diffuse un message, une seule fois par connexion, aux
+// joueurs abonnés à l'un des salons
+func PublishToRooms(message Envelope, rooms ...string) {
+	broadcast(message, func(c *client) bool {
+		if !isPlayerClient(c) {
+			return false
+		}
+		for _, room := range rooms {
+			if c.rooms[room] {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// SendToUser envoie un message privé à toutes les connexions de joueur d'un utilisateur
+func SendToUser(username string, message Envelope) {
+	broadcast(message, func(c *client) bool { return c.username == username && isPlayerClient(c) })
+}
+
+// SubscribeUser abonne toutes les connexions d'un utilisateur à un salon
+func SubscribeUser(username string, room string) {
+	clientsMutex.Lock()
+	defer clientsMutex.Unlock()
+	for c := range clients {
+		if c.username == username {
+			c.rooms[room] = true
+		}
+	}
+}
+
+// UnsubscribeUser désabonne toutes les connexions d'un utilisateur d'un salon
+func UnsubscribeUser(username string, room string) {
+	clientsMutex.Lock()
+	var unsubscribed []*client
+	for c := range clients {
+		if c.username == username {
+			delete(c.rooms, room)
+			unsubscribed = append(unsubscribed, c)
+		}
+	}
+	clientsMutex.Unlock()
+
+	for _, c := range unsubscribed {
+		forgetView(c, room)
+	}
+}
+
+// subscribe abonne une connexion à un salon
+func subscribe(c *client, room string) {
+	clientsMutex.Lock()
+	defer clientsMutex.Unlock()
+	c.rooms[room] = true
+}
+
+// unsubscribe désabonne une connexion d'un salon
+func unsubscribe(c *client, room string) {
+	clientsMutex.Lock()
+	delete(c.rooms, room)
+	clientsMutex.Unlock()
+	forgetView(c, room)
+}
+
+// hasRole indique si le token de la connexion porte l'un des rôles donnés
+func (c *client) hasRole(roles ...string) bool {
+	for _, role := range roles {
+		if slices.Contains(c.roles, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// sendToConnection envoie un message à une seule connexion
+func sendToConnection(c *client, message Envelope) {
+	messageBytes, err := c.format.marshal(message)
+	if err != nil {
+		log.Printf("Erreur lors de la sérialisation du message: %v", err)
+		return
+	}
+	c.enqueue(messageBytes)
+}
+
+// isUserConnected indique si un utilisateur a encore une connexion WebSocket
+// de joueur ouverte ; les flux SSE, en lecture seule, ne comptent pas
+func isUserConnected(username string) bool {
+	clientsMutex.RLock()
+	defer clientsMutex.RUnlock()
+
+	for c := range clients {
+		if c.username == username && isPlayerClient(c) && c.stream == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// GetActiveConnections retourne le nombre de connexions actives
+func GetActiveConnections() int {
+	clientsMutex.RLock()
+	defer clientsMutex.RUnlock()
+	return len(clients)
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlowConsumerIsClosed(t *testing.T) {
+	// Un délai d'écriture long : seule la file pleine peut fermer la connexion
+	url := newTestServer(t, WebSocketConfig{WriteWait: time.Minute})
+	dial(t, url, "slow-consumer")
+	fast := dial(t, url, "fast-consumer")
+
+	// Le client lent ne lit plus rien : la socket puis sa file se remplissent
+	payload := strings.Repeat("x", 64*1024)
+	for i := 0; i < 4*sendQueueSize && isUserConnected("slow-consumer"); i++ {
+		SendToUser("slow-consumer", NewEnvelope(MessageChat, payload))
+		time.Sleep(time.Millisecond)
+	}
+	eventually(t, "the slow consumer is disconnected", func() bool {
+		return Presence("slow-consumer") == PresenceDisconnected
+	})
+
+	// Les autres connexions ne sont pas touchées
+	send(t, fast, MessageAuth, EmptyPayload{})
+	readUntil(t, fast, MessageAuthenticated)
+	if Presence("fast-consumer") != PresenceOnline {
+		t.Fatal("closing the slow consumer disconnected another client")
+	}
+}
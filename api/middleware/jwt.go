@@ -1,11 +1,14 @@
 package middleware
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"time"
 
 	"net/http"
 
+	"github.com/becaraya/katana-api/internal/account"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -15,21 +18,64 @@ var (
 	ErrTokenExpired = errors.New("token is expired")
 )
 
+// Types de tokens émis par le serveur
+const (
+	TokenKindAccess  = "access"
+	TokenKindRefresh = "refresh"
+)
+
 type Claims struct {
-	Username string `json:"username"`
+	Username string   `json:"username"`
+	Guest    bool     `json:"guest,omitempty"`
+	Roles    []string `json:"roles,omitempty"`
+	Kind     string   `json:"kind"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(username string, secret string, expiry time.Duration) (string, error) {
-	claims := Claims{
+// GenerateToken signe un token et retourne ses claims, dont le jti
+func GenerateToken(username string, guest bool, roles []string, kind string, secret string, expiry time.Duration) (string, *Claims, error) {
+	id, err := NewTokenID()
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now()
+	claims := &Claims{
 		Username: username,
+		Guest:    guest,
+		Roles:    roles,
+		Kind:     kind,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiry)),
+			ID:        id,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiry)),
 		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	signed, err := token.SignedString([]byte(secret))
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, claims, nil
+}
+
+// NewTokenID génère un identifiant aléatoire de token ou de session
+func NewTokenID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// Info retourne la description du token portée par ses claims
+func (c *Claims) Info() account.TokenInfo {
+	info := account.TokenInfo{ID: c.ID, Username: c.Username, Kind: c.Kind}
+	if c.ExpiresAt != nil {
+		info.ExpiresAt = c.ExpiresAt.Time
+	}
+	return info
 }
 
 func ValidateToken(tokenString string, secret string) (*Claims, error) {
@@ -37,6 +83,9 @@ func ValidateToken(tokenString string, secret string) (*Claims, error) {
 		return []byte(secret), nil
 	})
 
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil, ErrTokenExpired
+	}
 	if err != nil {
 		return nil, ErrTokenInvalid
 	}
@@ -48,7 +97,23 @@ func ValidateToken(tokenString string, secret string) (*Claims, error) {
 	return nil, ErrTokenExpired
 }
 
-func JWTAuthMiddleware(secret string) gin.HandlerFunc {
+// ValidateAccessToken vérifie un access token et qu'il n'a pas été révoqué
+func ValidateAccessToken(tokenString string, secret string, tokens *TokenStore) (*Claims, error) {
+	claims, err := ValidateToken(tokenString, secret)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Kind != TokenKindAccess || claims.ExpiresAt == nil {
+		return nil, ErrTokenInvalid
+	}
+	if tokens.IsRevoked(claims.ID) {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
+}
+
+// JWTAuthMiddleware n'accepte que les access tokens valides et non révoqués
+func JWTAuthMiddleware(secret string, tokens *TokenStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tokenString := c.GetHeader("Authorization")
 		if tokenString == "" {
@@ -57,14 +122,29 @@ func JWTAuthMiddleware(secret string) gin.HandlerFunc {
 			return
 		}
 
-		claims, err := ValidateToken(tokenString, secret)
+		claims, err := ValidateAccessToken(tokenString, secret, tokens)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 			c.Abort()
 			return
 		}
 
+		c.Set("claims", claims)
 		c.Set("username", claims.Username)
+		c.Set("guest", claims.Guest)
+		c.Set("roles", claims.Roles)
+		c.Next()
+	}
+}
+
+// AccountRequiredMiddleware refuse les invités connectés sans compte
+func AccountRequiredMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetBool("guest") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "an account is required"})
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
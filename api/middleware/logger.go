@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// redactedQueryParams sont les paramètres dont la valeur ne doit jamais apparaître dans les logs
+var redactedQueryParams = []string{"token"}
+
+// Logger journalise les requêtes comme le logger de gin, sans recopier le
+// token que /ws accepte dans ses paramètres
+func Logger() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
+		var statusColor, methodColor, resetColor string
+		if param.IsOutputColor() {
+			statusColor = param.StatusCodeColor()
+			methodColor = param.MethodColor()
+			resetColor = param.ResetColor()
+		}
+		if param.Latency > time.Minute {
+			param.Latency = param.Latency.Truncate(time.Second)
+		}
+		return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %15s |%s %-7s %s %#v\n%s",
+			param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			statusColor, param.StatusCode, resetColor,
+			param.Latency,
+			param.ClientIP,
+			methodColor, param.Method, resetColor,
+			redactPath(param.Path),
+			param.ErrorMessage,
+		)
+	})
+}
+
+// redactPath masque la valeur des paramètres sensibles d'un chemin
+func redactPath(path string) string {
+	base, rawQuery, found := strings.Cut(path, "?")
+	if !found {
+		return path
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		// Une requête illisible n'est pas recopiée du tout
+		return base + "?REDACTED"
+	}
+	redacted := false
+	for _, param := range redactedQueryParams {
+		if _, exists := query[param]; exists {
+			query.Set(param, "REDACTED")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return path
+	}
+	return base + "?" + query.Encode()
+}
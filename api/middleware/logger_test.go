@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestLoggerRedactsToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var logs bytes.Buffer
+	defaultWriter := gin.DefaultWriter
+	gin.DefaultWriter = &logs
+	defer func() { gin.DefaultWriter = defaultWriter }()
+
+	router := gin.New()
+	router.Use(Logger())
+	router.GET("/ws", func(c *gin.Context) { c.String(http.StatusOK, c.Query("token")) })
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ws?v=1&token=secret.jwt.value", nil))
+	if rec.Body.String() != "secret.jwt.value" {
+		t.Fatalf("the handler got token %q", rec.Body.String())
+	}
+	if line := logs.String(); strings.Contains(line, "secret") || !strings.Contains(line, "/ws?token=REDACTED&v=1") {
+		t.Fatalf("log line %q leaks or loses the query", line)
+	}
+}
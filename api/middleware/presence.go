@@ -0,0 +1,69 @@
+package middleware
+
+// Statuts de présence d'un utilisateur, affichés dans le salon et en partie
+const (
+	PresenceOnline       = "online"
+	PresenceAway         = "away"
+	PresenceDisconnected = "disconnected"
+)
+
+// Presence retourne le statut d'un utilisateur : en ligne si l'une de ses
+// connexions WebSocket est active, absent si toutes sont inactives, déconnecté
+// sinon. Les flux SSE, en lecture seule, ne comptent pas.
+func Presence(username string) string {
+	clientsMutex.RLock()
+	defer clientsMutex.RUnlock()
+	return presenceLocked(username)
+}
+
+// GetPresences retourne le statut de chacun des utilisateurs donnés
+func GetPresences(usernames []string) map[string]string {
+	clientsMutex.RLock()
+	defer clientsMutex.RUnlock()
+
+	presences := make(map[string]string, len(usernames))
+	for _, username := range usernames {
+		presences[username] = presenceLocked(username)
+	}
+	return presences
+}
+
+func presenceLocked(username string) string {
+	status := PresenceDisconnected
+	for c := range clients {
+		if c.username != username || !isPlayerClient(c) || c.stream != "" {
+			continue
+		}
+		if !c.away {
+			return PresenceOnline
+		}
+		status = PresenceAway
+	}
+	return status
+}
+
+// setAway change l'état d'absence d'un client et diffuse la présence de son
+// utilisateur si elle a changé
+func setAway(c *client, away bool) {
+	clientsMutex.Lock()
+	if c.away == away {
+		clientsMutex.Unlock()
+		return
+	}
+	before := presenceLocked(c.username)
+	c.away = away
+	after := presenceLocked(c.username)
+	clientsMutex.Unlock()
+
+	if before != after {
+		broadcastPresence(c.username)
+	}
+}
+
+// broadcastPresence diffuse le statut courant d'un utilisateur
+func broadcastPresence(username string) {
+	BroadcastToAll(NewEnvelope(MessagePresence, PresencePayload{
+		Username: username,
+		Status:   Presence(username),
+	}))
+}
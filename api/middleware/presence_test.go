@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// readPresence lit les messages jusqu'au statut attendu d'un utilisateur
+func readPresence(t *testing.T, conn *websocket.Conn, username string, status string) {
+	t.Helper()
+	for {
+		var presence PresencePayload
+		if err := json.Unmarshal(readUntil(t, conn, MessagePresence).Payload, &presence); err != nil {
+			t.Fatal(err)
+		}
+		if presence.Username == username && presence.Status == status {
+			return
+		}
+	}
+}
+
+func TestIdleClientGoesAwayThenOnline(t *testing.T) {
+	url := newTestServer(t, WebSocketConfig{
+		PingInterval: 20 * time.Millisecond,
+		PongWait:     5 * time.Second,
+		AwayAfter:    100 * time.Millisecond,
+	})
+	observer := dial(t, url, "presence-observer")
+	idle := dial(t, url, "presence-idle")
+
+	readPresence(t, observer, "presence-idle", PresenceAway)
+	if Presence("presence-idle") != PresenceAway {
+		t.Fatalf("presence is %q after the idle delay", Presence("presence-idle"))
+	}
+
+	// N'importe quel message du client le remet en ligne
+	send(t, idle, MessageAuth, EmptyPayload{})
+	readPresence(t, observer, "presence-idle", PresenceOnline)
+}
+
+func TestHeartbeatClosesUnresponsiveClient(t *testing.T) {
+	url := newTestServer(t, WebSocketConfig{
+		PingInterval: 20 * time.Millisecond,
+		PongWait:     200 * time.Millisecond,
+	})
+	// Un client qui lit répond aux pings ; l'autre ne lit plus et n'y répond jamais
+	alive := dial(t, url, "heartbeat-alive")
+	go func() {
+		for {
+			if _, _, err := alive.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	dial(t, url, "heartbeat-silent")
+
+	eventually(t, "the silent client is disconnected", func() bool {
+		return Presence("heartbeat-silent") == PresenceDisconnected
+	})
+	time.Sleep(300 * time.Millisecond)
+	if Presence("heartbeat-alive") != PresenceOnline {
+		t.Fatalf("a client answering pings is %q", Presence("heartbeat-alive"))
+	}
+}
@@ -0,0 +1,359 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/becaraya/katana-api/internal/chat"
+	"github.com/becaraya/katana-api/internal/game"
+	"github.com/gin-gonic/gin"
+	"github.com/invopop/jsonschema"
+)
+
+//go:generate go run ../../cmd/schema -o ../../docs/websocket.schema.json
+
+// ProtocolVersion est la version courante du protocole WebSocket
+const ProtocolVersion = 1
+
+// SupportedVersions liste les versions du protocole acceptées à la connexion
+var SupportedVersions = []int{ProtocolVersion}
+
+// subprotocolPrefix préfixe les sous-protocoles WebSocket qui annoncent une
+// version et éventuellement un format, par exemple katana.v1 ou katana.v1.msgpack
+const subprotocolPrefix = "katana.v"
+
+var ErrUnsupportedVersion = errors.New("unsupported protocol version")
+
+// connectionProtocol est la version et le format négociés pour une connexion,
+// avec le sous-protocole à accepter à l'upgrade
+type connectionProtocol struct {
+	version     int
+	format      Format
+	subprotocol string
+}
+
+// MessageType identifie le type d'un message et donc la structure de son payload
+type MessageType string
+
+// Messages envoyés par le serveur
+const (
+	MessageHello           MessageType = "hello"
+	MessageError           MessageType = "error"
+	MessageAuthenticated   MessageType = "authenticated"
+	MessagePresence        MessageType = "presence"
+	MessageLogin           MessageType = "login"
+	MessageGameUpdate      MessageType = "game_update"
+	MessageGameDelta       MessageType = "game_delta"
+	MessageGameStarted     MessageType = "game_started"
+	MessageReplay          MessageType = "replay"
+	MessageSnapshot        MessageType = "snapshot"
+	MessageSubscribed      MessageType = "subscribed"
+	MessageSpectatorUpdate MessageType = "spectator_update"
+	MessagePrompt          MessageType = "prompt"
+	MessageChat            MessageType = "chat"
+	MessageChatHistory     MessageType = "chat_history"
+	MessageEmote           MessageType = "emote"
+)
+
+// Messages envoyés par le client
+const (
+	MessageAuth        MessageType = "auth"
+	MessageSubscribe   MessageType = "subscribe"
+	MessageUnsubscribe MessageType = "unsubscribe"
+	MessageResume      MessageType = "resume"
+	MessageResync      MessageType = "resync"
+	MessageSpectate    MessageType = "spectate"
+	MessageSetPresence MessageType = "presence"
+	MessagePostChat    MessageType = "chat"
+	MessageSendEmote   MessageType = "emote"
+)
+
+// Envelope est la forme commune de tous les messages envoyés par le serveur.
+// Seq est le numéro du dernier événement de la partie GameID, quand le
+// message concerne une partie.
+type Envelope struct {
+	Version   int         `json:"v"`
+	Type      MessageType `json:"type"`
+	GameID    string      `json:"game_id,omitempty"`
+	Seq       int64       `json:"seq,omitempty"`
+	Timestamp time.Time   `json:"ts"`
+	Payload   interface{} `json:"payload,omitempty"`
+}
+
+// ClientEnvelope est la forme des messages envoyés par le client ; Payload
+// est décodé selon Type
+type ClientEnvelope struct {
+	Version int             `json:"v,omitempty"`
+	Type    MessageType     `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// NewEnvelope construit un message du serveur qui ne concerne pas une partie
+func NewEnvelope(messageType MessageType, payload interface{}) Envelope {
+	return Envelope{
+		Version:   ProtocolVersion,
+		Type:      messageType,
+		Timestamp: time.Now(),
+		Payload:   payload,
+	}
+}
+
+// NewGameEnvelope construit un message du serveur au sujet d'une partie
+func NewGameEnvelope(messageType MessageType, g *game.Game, payload interface{}) Envelope {
+	envelope := NewEnvelope(messageType, payload)
+	envelope.GameID = g.ID
+	envelope.Seq = g.LastSeq()
+	return envelope
+}
+
+// Payloads des messages du serveur
+
+type HelloPayload struct {
+	Version           int      `json:"version"`
+	SupportedVersions []int    `json:"supported_versions"`
+	Format            Format   `json:"format" jsonschema:"enum=json,enum=msgpack"`
+	Username          string   `json:"username"`
+	Roles             []string `json:"roles,omitempty"`
+}
+
+type ErrorPayload struct {
+	Message string `json:"message"`
+}
+
+type AuthenticatedPayload struct {
+	Username string `json:"username"`
+}
+
+type PresencePayload struct {
+	Username string `json:"username"`
+	Status   string `json:"status" jsonschema:"enum=online,enum=away,enum=disconnected"`
+}
+
+type LoginPayload struct {
+	Username string    `json:"username"`
+	Expiry   time.Time `json:"expiry"`
+}
+
+// GameUpdatePayload et GameStartedPayload portent la vue publique de la
+// partie, copiée sous son verrou
+type GameUpdatePayload struct {
+	Event   string                      `json:"event"`
+	Game    *game.GameView              `json:"game"`
+	Players map[string]*game.PlayerView `json:"players"`
+	Message string                      `json:"message,omitempty"`
+}
+
+type GameStartedPayload struct {
+	Game *game.GameView `json:"game"`
+}
+
+// GameDeltaPayload accompagne game_delta : Ops s'applique à la vue de numéro
+// Base et donne celle de numéro Seq. Un client dont la vue n'est pas Base a
+// manqué un message et demande un resync.
+type GameDeltaPayload struct {
+	Event string           `json:"event"`
+	Base  int64            `json:"base"`
+	Ops   []PatchOperation `json:"ops"`
+}
+
+type ReplayPayload struct {
+	Events []game.Event `json:"events"`
+	Hand   []game.Card  `json:"hand"`
+}
+
+// GameViewPayload accompagne snapshot, subscribed et spectator_update, qui
+// envoient la vue complète servant de base aux deltas suivants
+type GameViewPayload struct {
+	Game *game.GameView `json:"game"`
+}
+
+type PromptPayload struct {
+	Reaction *game.Reaction `json:"reaction"`
+	Hand     []game.Card    `json:"hand"`
+}
+
+// ChatHistoryPayload accompagne chat_history, envoyé à ceux qui rejoignent un canal
+type ChatHistoryPayload struct {
+	Channel  string         `json:"channel"`
+	Messages []chat.Message `json:"messages"`
+}
+
+// EmotePayload accompagne emote ; EventSeq est le numéro de l'événement de la
+// partie auquel l'emote réagit, 0 si elle n'en vise aucun
+type EmotePayload struct {
+	From     string `json:"from"`
+	Emote    string `json:"emote"`
+	Label    string `json:"label"`
+	EventSeq int64  `json:"event_seq,omitempty"`
+}
+
+// Payloads des messages du client
+
+// GameRefPayload accompagne subscribe, unsubscribe, spectate et resync ; le salon
+// d'accueil se quitte avec game_id "lobby"
+type GameRefPayload struct {
+	GameID string `json:"game_id,omitempty"`
+}
+
+type ResumePayload struct {
+	GameID  string `json:"game_id,omitempty"`
+	LastSeq int64  `json:"last_seq"`
+}
+
+type SetPresencePayload struct {
+	Status string `json:"status" jsonschema:"enum=online,enum=away"`
+}
+
+// ChatPostPayload accompagne chat : channel est "lobby" ou l'identifiant d'une partie
+type ChatPostPayload struct {
+	Channel string `json:"channel"`
+	Text    string `json:"text"`
+}
+
+// SendEmotePayload accompagne emote : emote est l'identifiant d'une emote du
+// catalogue, event_seq celui d'un événement déjà survenu de la partie
+type SendEmotePayload struct {
+	GameID   string `json:"game_id"`
+	Emote    string `json:"emote"`
+	EventSeq int64  `json:"event_seq,omitempty"`
+}
+
+type EmptyPayload struct{}
+
+// ServerMessages associe chaque message du serveur à son payload
+var ServerMessages = map[MessageType]interface{}{
+	MessageHello:           HelloPayload{},
+	MessageError:           ErrorPayload{},
+	MessageAuthenticated:   AuthenticatedPayload{},
+	MessagePresence:        PresencePayload{},
+	MessageLogin:           LoginPayload{},
+	MessageGameUpdate:      GameUpdatePayload{},
+	MessageGameDelta:       GameDeltaPayload{},
+	MessageGameStarted:     GameStartedPayload{},
+	MessageReplay:          ReplayPayload{},
+	MessageSnapshot:        GameViewPayload{},
+	MessageSubscribed:      GameViewPayload{},
+	MessageSpectatorUpdate: GameViewPayload{},
+	MessagePrompt:          PromptPayload{},
+	MessageChat:            chat.Message{},
+	MessageChatHistory:     ChatHistoryPayload{},
+	MessageEmote:           EmotePayload{},
+}
+
+// ClientMessages associe chaque message du client à son payload
+var ClientMessages = map[MessageType]interface{}{
+	MessageAuth:        EmptyPayload{},
+	MessageSubscribe:   GameRefPayload{},
+	MessageUnsubscribe: GameRefPayload{},
+	MessageResume:      ResumePayload{},
+	MessageResync:      GameRefPayload{},
+	MessageSpectate:    GameRefPayload{},
+	MessageSetPresence: SetPresencePayload{},
+	MessagePostChat:    ChatPostPayload{},
+	MessageSendEmote:   SendEmotePayload{},
+}
+
+// negotiateProtocol choisit la version et le format d'une connexion : ceux
+// d'un sous-protocole katana.vN ou katana.vN.format, sinon la version du
+// paramètre v en JSON, sinon la plus récente en JSON
+func negotiateProtocol(r *http.Request) (connectionProtocol, error) {
+	announced := false
+	for _, subprotocol := range websocketProtocols(r) {
+		if !strings.HasPrefix(subprotocol, subprotocolPrefix) {
+			continue
+		}
+		announced = true
+		version, format, _ := strings.Cut(strings.TrimPrefix(subprotocol, subprotocolPrefix), ".")
+		number, err := strconv.Atoi(version)
+		if format == "" {
+			format = string(FormatJSON)
+		}
+		if err == nil && slices.Contains(SupportedVersions, number) && slices.Contains(Formats, Format(format)) {
+			return connectionProtocol{version: number, format: Format(format), subprotocol: subprotocol}, nil
+		}
+	}
+	if announced {
+		return connectionProtocol{}, ErrUnsupportedVersion
+	}
+
+	requested := r.URL.Query().Get("v")
+	if requested == "" {
+		return connectionProtocol{version: ProtocolVersion, format: FormatJSON}, nil
+	}
+	version, err := strconv.Atoi(requested)
+	if err != nil || !slices.Contains(SupportedVersions, version) {
+		return connectionProtocol{}, ErrUnsupportedVersion
+	}
+	return connectionProtocol{version: version, format: FormatJSON}, nil
+}
+
+// websocketProtocols retourne les sous-protocoles proposés par le client
+func websocketProtocols(r *http.Request) []string {
+	var protocols []string
+	for _, header := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, protocol := range strings.Split(header, ",") {
+			if protocol = strings.TrimSpace(protocol); protocol != "" {
+				protocols = append(protocols, protocol)
+			}
+		}
+	}
+	return protocols
+}
+
+// ProtocolSchema génère le JSON Schema du protocole à partir des types Go :
+// l'enveloppe des messages du serveur et du client, et chaque payload
+func ProtocolSchema() *jsonschema.Schema {
+	reflector := &jsonschema.Reflector{ExpandedStruct: true, Anonymous: true}
+	schema := &jsonschema.Schema{
+		Version:     jsonschema.Version,
+		Title:       "Katana WebSocket protocol v" + strconv.Itoa(ProtocolVersion),
+		Definitions: jsonschema.Definitions{},
+	}
+
+	// reflect génère le schéma d'un type et remonte ses définitions à la racine
+	reflect := func(v interface{}) *jsonschema.Schema {
+		reflected := reflector.Reflect(v)
+		for key, definition := range reflected.Definitions {
+			schema.Definitions[key] = definition
+		}
+		reflected.Definitions = nil
+		reflected.Version = ""
+		return reflected
+	}
+
+	// messages génère l'enveloppe d'une famille de messages, avec une variante par type
+	messages := func(envelope interface{}, payloads map[MessageType]interface{}) *jsonschema.Schema {
+		types := make([]string, 0, len(payloads))
+		for messageType := range payloads {
+			types = append(types, string(messageType))
+		}
+		slices.Sort(types)
+
+		root := reflect(envelope)
+		for _, messageType := range types {
+			properties := jsonschema.NewProperties()
+			properties.Set("type", &jsonschema.Schema{Const: messageType})
+			properties.Set("payload", reflect(payloads[MessageType(messageType)]))
+			root.OneOf = append(root.OneOf, &jsonschema.Schema{Title: messageType, Properties: properties})
+		}
+		return root
+	}
+
+	schema.Definitions["ServerMessage"] = messages(Envelope{}, ServerMessages)
+	schema.Definitions["ClientMessage"] = messages(ClientEnvelope{}, ClientMessages)
+	schema.OneOf = []*jsonschema.Schema{
+		{Ref: "#/$defs/ServerMessage"},
+		{Ref: "#/$defs/ClientMessage"},
+	}
+	return schema
+}
+
+// ProtocolSchemaHandler sert le JSON Schema du protocole
+func ProtocolSchemaHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, ProtocolSchema())
+}
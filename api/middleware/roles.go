@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Rôles portés par les tokens
+const (
+	RolePlayer    = "player"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// Roles liste les rôles reconnus
+var Roles = []string{RolePlayer, RoleModerator, RoleAdmin}
+
+// HasRole indique si l'utilisateur authentifié possède un rôle
+func HasRole(c *gin.Context, role string) bool {
+	for _, r := range c.GetStringSlice("roles") {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireRole n'accepte que les utilisateurs possédant l'un des rôles donnés
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, role := range roles {
+			if HasRole(c, role) {
+				c.Next()
+				return
+			}
+		}
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		c.Abort()
+	}
+}
@@ -1,42 +1,185 @@
 package middleware
 
 import (
+	"errors"
+	"log"
 	"sync"
 	"time"
+
+	"github.com/becaraya/katana-api/internal/account"
+)
+
+var (
+	ErrTokenRevoked = errors.New("token is revoked")
+	ErrTokenReused  = errors.New("refresh token was already used")
 )
 
 type TokenStore struct {
-	mu     sync.Mutex
-	tokens map[string]time.Time
+	mu         sync.Mutex
+	tokens     map[string]account.TokenInfo
+	revoked    map[string]account.TokenInfo
+	repository account.TokenRepository
 }
 
 func NewTokenStore() *TokenStore {
 	store := &TokenStore{
-		tokens: make(map[string]time.Time),
+		tokens:  make(map[string]account.TokenInfo),
+		revoked: make(map[string]account.TokenInfo),
 	}
 	go store.cleanupExpiredTokens()
 	return store
 }
 
-func (s *TokenStore) AddToken(username string, expiry time.Time) {
+// SetRepository branche le stockage des tokens et restaure les tokens
+// encore valides qu'il contient
+func (s *TokenStore) SetRepository(repository account.TokenRepository) error {
+	issued, revoked, err := repository.LoadAll()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.repository = repository
+	now := time.Now()
+	for _, info := range issued {
+		if now.Before(info.ExpiresAt) {
+			s.tokens[info.ID] = info
+		}
+	}
+	for _, info := range revoked {
+		if now.Before(info.ExpiresAt) {
+			delete(s.tokens, info.ID)
+			s.revoked[info.ID] = info
+		}
+	}
+	return nil
+}
+
+func (s *TokenStore) AddToken(info account.TokenInfo) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.tokens[username] = expiry
+	s.tokens[info.ID] = info
+	s.save(info, false)
 }
 
-func (s *TokenStore) GetTokens() map[string]time.Time {
+func (s *TokenStore) GetTokens() []account.TokenInfo {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	return s.tokens
+	tokens := make([]account.TokenInfo, 0, len(s.tokens))
+	for _, info := range s.tokens {
+		tokens = append(tokens, info)
+	}
+	return tokens
+}
+
+// Get retourne un token émis et toujours valide
+func (s *TokenStore) Get(id string) (account.TokenInfo, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	info, ok := s.tokens[id]
+	return info, ok
+}
+
+// IsRevoked indique si un token a été révoqué
+func (s *TokenStore) IsRevoked(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.revoked[id]
+	return ok
+}
+
+// Revoke révoque un token jusqu'à son expiration, ainsi que sa session
+// lorsqu'elle est connue
+func (s *TokenStore) Revoke(info account.TokenInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if issued, ok := s.tokens[info.ID]; ok {
+		info = issued
+	}
+	delete(s.tokens, info.ID)
+	s.revoked[info.ID] = info
+	s.save(info, true)
+	if info.Session != "" {
+		s.revokeWhere(func(other account.TokenInfo) bool { return other.Session == info.Session })
+	}
+}
+
+// RevokeUser révoque tous les tokens d'un utilisateur
+func (s *TokenStore) RevokeUser(username string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.revokeWhere(func(info account.TokenInfo) bool { return info.Username == username })
+}
+
+// Redeem consomme un refresh token : il ne peut servir qu'une fois, et sa
+// réutilisation révoque toute la session
+func (s *TokenStore) Redeem(id string) (account.TokenInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if used, ok := s.revoked[id]; ok {
+		s.revokeWhere(func(info account.TokenInfo) bool { return info.Session == used.Session })
+		return account.TokenInfo{}, ErrTokenReused
+	}
+
+	info, ok := s.tokens[id]
+	if !ok || info.Kind != TokenKindRefresh {
+		return account.TokenInfo{}, ErrTokenInvalid
+	}
+	delete(s.tokens, id)
+	s.revoked[id] = info
+	s.save(info, true)
+	return info, nil
+}
+
+// revokeWhere révoque les tokens qui vérifient le prédicat, verrou tenu
+func (s *TokenStore) revokeWhere(match func(account.TokenInfo) bool) {
+	for id, info := range s.tokens {
+		if match(info) {
+			delete(s.tokens, id)
+			s.revoked[id] = info
+			s.save(info, true)
+		}
+	}
+}
+
+// save enregistre un token émis ou révoqué, verrou tenu
+func (s *TokenStore) save(info account.TokenInfo, revoked bool) {
+	if s.repository == nil {
+		return
+	}
+	if err := s.repository.Save(info, revoked); err != nil {
+		log.Printf("Erreur lors de la sauvegarde du token %s: %v", info.ID, err)
+	}
+}
+
+// forget oublie un token expiré, verrou tenu
+func (s *TokenStore) forget(id string) {
+	if s.repository == nil {
+		return
+	}
+	if err := s.repository.Delete(id); err != nil {
+		log.Printf("Erreur lors de la suppression du token %s: %v", id, err)
+	}
 }
 
 func (s *TokenStore) cleanupExpiredTokens() {
 	for {
 		time.Sleep(1 * time.Hour) // Run cleanup every hour
 		s.mu.Lock()
-		for username, expiry := range s.tokens {
-			if time.Now().After(expiry) {
-				delete(s.tokens, username)
+		now := time.Now()
+		for id, info := range s.tokens {
+			if now.After(info.ExpiresAt) {
+				delete(s.tokens, id)
+				s.forget(id)
+			}
+		}
+		for id, info := range s.revoked {
+			if now.After(info.ExpiresAt) {
+				delete(s.revoked, id)
+				s.forget(id)
 			}
 		}
 		s.mu.Unlock()
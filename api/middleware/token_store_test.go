@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+
+	"github.com/becaraya/katana-api/internal/account"
+)
+
+// memoryTokenRepository simule le stockage sur disque des tokens
+type memoryTokenRepository struct {
+	tokens  map[string]account.TokenInfo
+	revoked map[string]bool
+}
+
+func newMemoryTokenRepository() *memoryTokenRepository {
+	return &memoryTokenRepository{tokens: make(map[string]account.TokenInfo), revoked: make(map[string]bool)}
+}
+
+func (r *memoryTokenRepository) Save(info account.TokenInfo, revoked bool) error {
+	r.tokens[info.ID] = info
+	r.revoked[info.ID] = revoked
+	return nil
+}
+
+func (r *memoryTokenRepository) Delete(id string) error {
+	delete(r.tokens, id)
+	delete(r.revoked, id)
+	return nil
+}
+
+func (r *memoryTokenRepository) LoadAll() ([]account.TokenInfo, []account.TokenInfo, error) {
+	var issued, revoked []account.TokenInfo
+	for id, info := range r.tokens {
+		if r.revoked[id] {
+			revoked = append(revoked, info)
+		} else {
+			issued = append(issued, info)
+		}
+	}
+	return issued, revoked, nil
+}
+
+// restart recrée un registre à partir du même stockage, comme au redémarrage du serveur
+func restart(t *testing.T, repository account.TokenRepository) *TokenStore {
+	t.Helper()
+	store := NewTokenStore()
+	if err := store.SetRepository(repository); err != nil {
+		t.Fatalf("SetRepository: %v", err)
+	}
+	return store
+}
+
+func TestTokenStoreSurvivesRestart(t *testing.T) {
+	repository := newMemoryTokenRepository()
+	expires := time.Now().Add(time.Hour)
+	store := restart(t, repository)
+	store.AddToken(account.TokenInfo{ID: "alice-access", Session: "alice", Username: "alice", Kind: TokenKindAccess, ExpiresAt: expires})
+	store.AddToken(account.TokenInfo{ID: "alice-refresh", Session: "alice", Username: "alice", Kind: TokenKindRefresh, ExpiresAt: expires})
+	store.AddToken(account.TokenInfo{ID: "bob-access", Session: "bob", Username: "bob", Kind: TokenKindAccess, ExpiresAt: expires})
+	store.AddToken(account.TokenInfo{ID: "bob-refresh", Session: "bob", Username: "bob", Kind: TokenKindRefresh, ExpiresAt: expires})
+	store.AddToken(account.TokenInfo{ID: "expired", Username: "carol", Kind: TokenKindRefresh, ExpiresAt: time.Now().Add(-time.Hour)})
+	store.Revoke(account.TokenInfo{ID: "bob-access"})
+
+	// Un refresh token émis avant le redémarrage reste échangeable
+	store = restart(t, repository)
+	if _, err := store.Redeem("alice-refresh"); err != nil {
+		t.Fatalf("redeem after restart: %v", err)
+	}
+	if _, exists := store.Get("expired"); exists {
+		t.Fatal("an expired token was restored")
+	}
+
+	// Les révocations survivent, comme la consommation du refresh token
+	store = restart(t, repository)
+	if !store.IsRevoked("bob-access") || !store.IsRevoked("bob-refresh") {
+		t.Fatal("bob's session is no longer revoked after a restart")
+	}
+	if _, err := store.Redeem("alice-refresh"); err != ErrTokenReused {
+		t.Fatalf("replayed refresh token after restart: got %v, want %v", err, ErrTokenReused)
+	}
+
+	// RevokeUser voit les tokens émis avant le redémarrage
+	store = restart(t, repository)
+	store.AddToken(account.TokenInfo{ID: "alice-new", Username: "alice", Kind: TokenKindAccess, ExpiresAt: expires})
+	store = restart(t, repository)
+	store.RevokeUser("alice")
+	if !store.IsRevoked("alice-new") {
+		t.Fatal("RevokeUser missed a token issued before the restart")
+	}
+}
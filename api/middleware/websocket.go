@@ -5,7 +5,9 @@ import (
 	"log"
 	"net/http"
 	"sync"
+	"time"
 
+	"github.com/becaraya/katana-api/internal/game"
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 )
@@ -17,175 +19,485 @@ var (
 		},
 	}
 
-	// Stockage des connexions WebSocket actives
-	connections      = make(map[*websocket.Conn]string)
-	connectionsMutex sync.RWMutex
-
 	connectedUsers      = make(map[string]bool)
 	connectedUsersMutex sync.RWMutex
 )
 
-// Message structure pour WebSocket
-type WSMessage struct {
-	Type string      `json:"type"`
-	Data interface{} `json:"data"`
-	From string      `json:"from,omitempty"`
-}
+// revocationCheckInterval est la fréquence à laquelle une connexion vérifie
+// que son token n'a pas été révoqué
+const revocationCheckInterval = 5 * time.Second
 
-// WebSocketHandler gère les connexions WebSocket
-func WebSocketHandler(c *gin.Context) {
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
-	if err != nil {
-		log.Printf("Erreur lors de l'upgrade WebSocket: %v", err)
-		return
-	}
-	defer conn.Close()
+// WebSocketConfig regroupe les réglages des connexions WebSocket
+type WebSocketConfig struct {
+	TokenSecret    string
+	Tokens         *TokenStore
+	ReconnectGrace time.Duration
 
-	// Ajouter la connexion à la liste
-	connectionsMutex.Lock()
-	connections[conn] = ""
-	connectionsMutex.Unlock()
+	// PingInterval est l'intervalle des pings, PongWait le délai au-delà
+	// duquel une connexion sans pong ni message est considérée comme morte
+	PingInterval   time.Duration
+	PongWait       time.Duration
+	WriteWait      time.Duration
+	MaxMessageSize int64
 
-	// Nettoyer la connexion à la fermeture
-	defer func() {
-		connectionsMutex.Lock()
-		username := connections[conn]
-		delete(connections, conn)
-		connectionsMutex.Unlock()
+	// AwayAfter est l'inactivité après laquelle un client passe absent (0 : jamais)
+	AwayAfter time.Duration
+}
 
-		// Supprimer de la liste des utilisateurs connectés
-		if username != "" {
-			connectedUsersMutex.Lock()
-			delete(connectedUsers, username)
-			connectedUsersMutex.Unlock()
+// withDefaults complète les réglages de heartbeat non renseignés
+func (config WebSocketConfig) withDefaults() WebSocketConfig {
+	if config.PongWait <= 0 {
+		config.PongWait = 60 * time.Second
+	}
+	if config.PingInterval <= 0 || config.PingInterval >= config.PongWait {
+		config.PingInterval = config.PongWait * 9 / 10
+	}
+	if config.WriteWait <= 0 {
+		config.WriteWait = 10 * time.Second
+	}
+	if config.MaxMessageSize <= 0 {
+		config.MaxMessageSize = 8192
+	}
+	return config
+}
 
-			log.Printf("Utilisateur %s déconnecté", username)
+// WebSocketHandler gère les connexions WebSocket. L'upgrade exige un access
+// token valide, passé dans le paramètre token ou l'en-tête Authorization :
+// l'identité de la connexion est celle du token, et la connexion est fermée
+// quand il expire ou est révoqué. La version du protocole est négociée par le
+// sous-protocole katana.vN ou le paramètre v, et annoncée dans le message hello.
+func WebSocketHandler(config WebSocketConfig) gin.HandlerFunc {
+	config = config.withDefaults()
+	return func(c *gin.Context) {
+		tokenString := c.Query("token")
+		if tokenString == "" {
+			tokenString = c.GetHeader("Authorization")
 		}
-	}()
+		if tokenString == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing token"})
+			return
+		}
+		claims, err := ValidateAccessToken(tokenString, config.TokenSecret, config.Tokens)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		username := claims.Username
 
-	// Écouter les messages entrants
-	for {
-		var message WSMessage
-		err := conn.ReadJSON(&message)
+		protocol, err := negotiateProtocol(c.Request)
 		if err != nil {
-			log.Printf("Erreur lecture WebSocket: %v", err)
-			break
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":              err.Error(),
+				"supported_versions": SupportedVersions,
+				"formats":            Formats,
+			})
+			return
+		}
+		var responseHeader http.Header
+		if protocol.subprotocol != "" {
+			responseHeader = http.Header{"Sec-WebSocket-Protocol": {protocol.subprotocol}}
 		}
 
-		// Traiter le message selon son type
-		handleWebSocketMessage(conn, message)
-	}
-}
+		conn, err := upgrader.Upgrade(c.Writer, c.Request, responseHeader)
+		if err != nil {
+			log.Printf("Erreur lors de l'upgrade WebSocket: %v", err)
+			return
+		}
 
-// Traiter les messages WebSocket entrants
-func handleWebSocketMessage(conn *websocket.Conn, message WSMessage) {
-	switch message.Type {
-	case "auth":
-		// Authentifier l'utilisateur et associer le username à la connexion
-		if data, ok := message.Data.(map[string]interface{}); ok {
-			if username, exists := data["username"]; exists {
-				connectionsMutex.Lock()
-				connections[conn] = username.(string)
-				connectionsMutex.Unlock()
+		// Une connexion qui ne répond plus aux pings est fermée à l'expiration du délai de lecture
+		conn.SetReadLimit(config.MaxMessageSize)
+		conn.SetReadDeadline(time.Now().Add(config.PongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(config.PongWait))
+		})
+
+		// Ajouter la connexion à la liste ; sa goroutine d'écriture s'arrête à la fermeture
+		client := newClient(conn, claims, protocol, config)
+		defer client.close()
+		for _, g := range game.GetGameManager().GetGames() {
+			if g.HasPlayer(username) && !g.IsKicked(username) {
+				client.rooms[g.ID] = true
+			}
+		}
+		registerClient(client)
+		go client.writePump()
+		sendToConnection(client, NewEnvelope(MessageHello, HelloPayload{
+			Version:           protocol.version,
+			SupportedVersions: SupportedVersions,
+			Format:            protocol.format,
+			Username:          username,
+			Roles:             claims.Roles,
+		}))
+		for room := range client.rooms {
+			sendChatHistory(client, room)
+		}
+
+		connectedUsersMutex.Lock()
+		connectedUsers[username] = true
+		connectedUsersMutex.Unlock()
+
+		log.Printf("Utilisateur %s connecté via WebSocket", username)
+		broadcastPresence(username)
+		reclaimSeats(username)
+
+		go watchToken(client, claims, config.Tokens)
 
-				log.Printf("Utilisateur %s connecté via WebSocket", username)
+		// Nettoyer la connexion à la fermeture
+		defer func() {
+			wasPlayer := !stopSpectating(client)
+			unregisterClient(client)
 
+			// Supprimer de la liste des utilisateurs connectés
+			if wasPlayer && !isUserConnected(username) {
 				connectedUsersMutex.Lock()
-				connectedUsers[username.(string)] = true
+				delete(connectedUsers, username)
 				connectedUsersMutex.Unlock()
+
+				log.Printf("Utilisateur %s déconnecté", username)
+				broadcastPresence(username)
+				reserveSeats(username, config.ReconnectGrace)
 			}
-		}
+		}()
+
+		// Écouter les messages entrants
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				log.Printf("Erreur lecture WebSocket: %v", err)
+				break
+			}
+			conn.SetReadDeadline(time.Now().Add(config.PongWait))
+			client.touch()
 
-	case "join_game":
-		// Diffuser l'information que quelqu'un a rejoint
-		if data, ok := message.Data.(map[string]interface{}); ok {
-			if username, exists := data["username"]; exists {
-				broadcastMessage := WSMessage{
-					Type: "player_joined",
-					Data: map[string]interface{}{
-						"username": username,
-						"message":  username.(string) + " a rejoint la partie!",
-					},
-					From: username.(string),
-				}
-				BroadcastToAll(broadcastMessage)
+			message, err := decodeClientMessage(client.format, data)
+			if err != nil {
+				sendError(client, "invalid message")
+				continue
+			}
+			if message.Type != MessageSetPresence {
+				setAway(client, false)
 			}
+
+			// Traiter le message selon son type
+			handleWebSocketMessage(client, message)
 		}
+	}
+}
+
+// watchToken ferme la connexion quand son token expire ou est révoqué
+func watchToken(c *client, claims *Claims, tokens *TokenStore) {
+	expiry := time.NewTimer(time.Until(claims.ExpiresAt.Time))
+	defer expiry.Stop()
+	ticker := time.NewTicker(revocationCheckInterval)
+	defer ticker.Stop()
 
-	case "leave_game":
-		// Diffuser l'information que quelqu'un a quitté
-		if data, ok := message.Data.(map[string]interface{}); ok {
-			if username, exists := data["username"]; exists {
-				broadcastMessage := WSMessage{
-					Type: "player_left",
-					Data: map[string]interface{}{
-						"username": username,
-						"message":  username.(string) + " a quitté la partie!",
-					},
-					From: username.(string),
-				}
-				BroadcastToAll(broadcastMessage)
+	reason := ""
+	for reason == "" {
+		select {
+		case <-c.done:
+			return
+		case <-expiry.C:
+			reason = ErrTokenExpired.Error()
+		case <-ticker.C:
+			if tokens.IsRevoked(claims.ID) {
+				reason = ErrTokenRevoked.Error()
 			}
 		}
+	}
 
-	case "start_game":
-		// Diffuser le démarrage du jeu
-		if data, ok := message.Data.(map[string]interface{}); ok {
-			if username, exists := data["username"]; exists {
-				broadcastMessage := WSMessage{
-					Type: "game_starting",
-					Data: map[string]interface{}{
-						"username": username,
-						"message":  "La partie va commencer...",
-					},
-					From: username.(string),
-				}
-				BroadcastToAll(broadcastMessage)
+	log.Printf("Connexion de %s fermée: %s", claims.Username, reason)
+	if c.conn != nil {
+		message := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, reason)
+		c.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(time.Second))
+	}
+	c.close()
+}
+
+// reserveSeats garde les sièges d'un joueur déconnecté pendant la période de grâce
+func reserveSeats(username string, grace time.Duration) {
+	for _, g := range game.GetGameManager().GetGames() {
+		if !g.MarkDisconnected(username) {
+			continue
+		}
+
+		// Mettre en pause les parties qui attendaient ce joueur
+		event := game.EventPlayerDisconnected
+		if g.PauseForDisconnect(username) {
+			event = game.EventGamePaused
+		}
+		BroadcastGameUpdate(g, event)
+
+		g := g
+		time.AfterFunc(grace, func() {
+			if g.ReleaseSeat(username, grace) {
+				BroadcastGameUpdate(g, game.EventSeatReleased)
 			}
+		})
+	}
+}
+
+// reclaimSeats rend à un joueur qui se reconnecte les sièges réservés pendant son absence
+func reclaimSeats(username string) {
+	for _, g := range game.GetGameManager().GetGames() {
+		if g.Reconnect(username) {
+			BroadcastGameUpdate(g, game.EventPlayerReconnected)
 		}
 	}
 }
 
-// BroadcastToAll diffuse un message à toutes les connexions actives
-func BroadcastToAll(message WSMessage) {
-	connectionsMutex.RLock()
-	defer connectionsMutex.RUnlock()
+// resumeSession renvoie à un joueur reconnecté ce qu'il a manqué ; son siège
+// lui a déjà été rendu à l'upgrade
+func resumeSession(c *client, request ResumePayload) {
+	username := c.username
+	gameManager := game.GetGameManager()
+	currentGame := gameManager.GetCurrentGame()
+	if request.GameID != "" {
+		currentGame = gameManager.GetGame(request.GameID)
+	}
+	if currentGame == nil || !currentGame.HasPlayer(username) {
+		return
+	}
+	if currentGame.IsKicked(username) {
+		sendError(c, "kicked from this game")
+		return
+	}
+	subscribe(c, currentGame.ID)
+
+	// Rejouer les événements manqués s'ils sont encore en mémoire, puis
+	// envoyer l'état complet sur lequel s'appliqueront les deltas
+	if events, ok := currentGame.EventsSince(request.LastSeq); ok {
+		sendToConnection(c, NewGameEnvelope(MessageReplay, currentGame, ReplayPayload{
+			Events: events,
+			Hand:   currentGame.Hand(username),
+		}))
+	}
+	sendSnapshot(c, currentGame, MessageSnapshot)
+	sendChatHistory(c, currentGame.ID)
+}
 
-	messageBytes, err := json.Marshal(message)
-	if err != nil {
-		log.Printf("Erreur lors de la sérialisation du message: %v", err)
+// resync renvoie l'état complet d'une partie suivie à un client qui a
+// détecté un trou dans les numéros de ses deltas
+func resync(c *client, request GameRefPayload) {
+	g := game.GetGameManager().GetGame(request.GameID)
+	if g == nil {
+		sendError(c, "game not found")
+		return
+	}
+	clientsMutex.RLock()
+	spectating := c.spectating == g
+	following := c.rooms[g.ID] || spectating
+	clientsMutex.RUnlock()
+	if !following {
+		sendError(c, "not subscribed to this game")
 		return
 	}
 
-	for conn := range connections {
-		err := conn.WriteMessage(websocket.TextMessage, messageBytes)
-		if err != nil {
-			log.Printf("Erreur lors de l'envoi du message WebSocket: %v", err)
-			// Supprimer la connexion fermée
-			delete(connections, conn)
-		}
+	if spectating {
+		sendSnapshot(c, g, MessageSpectatorUpdate)
+		return
 	}
+	sendSnapshot(c, g, MessageSnapshot)
 }
 
-// BroadcastMessage fonction utilitaire pour broadcaster des messages JSON
-func BroadcastMessage(messageBytes []byte) {
-	connectionsMutex.RLock()
-	defer connectionsMutex.RUnlock()
+// startSpectating abonne une connexion à la vue publique d'une partie
+func startSpectating(c *client, request GameRefPayload) {
+	gameManager := game.GetGameManager()
+	spectated := gameManager.GetCurrentGame()
+	if request.GameID != "" {
+		spectated = gameManager.GetGame(request.GameID)
+	}
+	if spectated == nil {
+		sendError(c, "game not found")
+		return
+	}
+	if err := spectated.AddSpectator(c.username); err != nil {
+		sendError(c, err.Error())
+		return
+	}
+
+	// Un spectateur ne reçoit plus les diffusions destinées aux joueurs
+	stopSpectating(c)
+	clientsMutex.Lock()
+	c.spectating = spectated
+	clientsMutex.Unlock()
 
-	for conn := range connections {
-		err := conn.WriteMessage(websocket.TextMessage, messageBytes)
-		if err != nil {
-			log.Printf("Erreur lors de l'envoi du message: %v", err)
-			delete(connections, conn)
+	sendSnapshot(c, spectated, MessageSpectatorUpdate)
+	BroadcastGameUpdate(spectated, "spectator_joined")
+}
+
+// subscribeToGame abonne une connexion au salon d'une partie : seuls ses
+// joueurs et les modérateurs y ont accès, les autres passent par spectate
+func subscribeToGame(c *client, request GameRefPayload) {
+	g := game.GetGameManager().GetGame(request.GameID)
+	if g == nil {
+		sendError(c, "game not found")
+		return
+	}
+	if (!g.HasPlayer(c.username) || g.IsKicked(c.username)) && !c.hasRole(RoleModerator, RoleAdmin) {
+		sendError(c, "not a player of this game, spectate it instead")
+		return
+	}
+
+	subscribe(c, g.ID)
+	sendSnapshot(c, g, MessageSubscribed)
+	sendChatHistory(c, g.ID)
+}
+
+// stopSpectating retire une connexion des spectateurs de sa partie, et
+// indique si elle regardait une partie
+func stopSpectating(c *client) bool {
+	clientsMutex.Lock()
+	spectated := c.spectating
+	c.spectating = nil
+	clientsMutex.Unlock()
+
+	if spectated == nil {
+		return false
+	}
+	forgetView(c, spectated.ID)
+	spectated.RemoveSpectator()
+	BroadcastGameUpdate(spectated, "spectator_left")
+	return true
+}
+
+// sendError envoie un message d'erreur à une connexion
+func sendError(c *client, message string) {
+	sendToConnection(c, NewEnvelope(MessageError, ErrorPayload{Message: message}))
+}
+
+// decodePayload décode le payload d'un message du client dans sa structure
+func decodePayload(message ClientEnvelope, payload interface{}) bool {
+	if len(message.Payload) == 0 {
+		return true
+	}
+	return json.Unmarshal(message.Payload, payload) == nil
+}
+
+// Traiter les messages WebSocket entrants au nom de l'utilisateur du token
+func handleWebSocketMessage(c *client, message ClientEnvelope) {
+	username := c.username
+
+	switch message.Type {
+	case MessageSpectate:
+		// Regarder une partie sans y jouer
+		var request GameRefPayload
+		if decodePayload(message, &request) {
+			startSpectating(c, request)
+			return
 		}
+
+	case MessageSubscribe:
+		// Suivre les mises à jour d'une partie
+		var request GameRefPayload
+		if decodePayload(message, &request) {
+			subscribeToGame(c, request)
+			return
+		}
+
+	case MessageUnsubscribe:
+		// Ne plus suivre une partie, ou le salon d'accueil avec game_id "lobby"
+		var request GameRefPayload
+		if decodePayload(message, &request) {
+			unsubscribe(c, request.GameID)
+			return
+		}
+
+	case MessageResume:
+		// Reprendre une session à partir du dernier événement reçu
+		var request ResumePayload
+		if decodePayload(message, &request) {
+			resumeSession(c, request)
+			return
+		}
+
+	case MessageResync:
+		// Renvoyer l'état complet d'une partie après un trou dans les deltas
+		var request GameRefPayload
+		if decodePayload(message, &request) {
+			resync(c, request)
+			return
+		}
+
+	case MessagePostChat:
+		// Écrire dans le chat du salon d'accueil ou d'une partie
+		var request ChatPostPayload
+		if decodePayload(message, &request) {
+			postChat(c, request)
+			return
+		}
+
+	case MessageSendEmote:
+		// Envoyer une emote à la table, éventuellement en réaction à un événement
+		var request SendEmotePayload
+		if decodePayload(message, &request) {
+			sendEmote(c, request)
+			return
+		}
+
+	case MessageSetPresence:
+		// Le client signale lui-même qu'il est absent ou de retour
+		var request SetPresencePayload
+		if decodePayload(message, &request) {
+			setAway(c, request.Status == PresenceAway)
+			return
+		}
+
+	case MessageAuth:
+		// L'identité vient du token de l'upgrade : on la confirme simplement
+		sendToConnection(c, NewEnvelope(MessageAuthenticated, AuthenticatedPayload{Username: username}))
+		return
+
+	default:
+		sendError(c, "unknown message type: "+string(message.Type))
+		return
 	}
+	sendError(c, "invalid payload for "+string(message.Type))
 }
 
-// GetActiveConnections retourne le nombre de connexions actives
-func GetActiveConnections() int {
-	connectionsMutex.RLock()
-	defer connectionsMutex.RUnlock()
-	return len(connections)
+// BroadcastGameUpdate diffuse le changement d'une partie avec l'événement qui
+// l'a provoqué : chaque connexion qui la suit, joueur ou spectateur, reçoit le
+// delta de sa propre vue, et le salon d'accueil l'état public tant que la
+// partie attend des joueurs. Le joueur dont une réaction est attendue la
+// reçoit en privé.
+func BroadcastGameUpdate(g *game.Game, event string) {
+	waiting := g.GetState() == game.GameStateWaiting
+
+	var followers, lobby []*client
+	clientsMutex.RLock()
+	for c := range clients {
+		switch {
+		case c.spectating == g || c.rooms[g.ID]:
+			followers = append(followers, c)
+		case waiting && c.spectating == nil && c.rooms[LobbyRoom]:
+			lobby = append(lobby, c)
+		}
+	}
+	clientsMutex.RUnlock()
+
+	for _, c := range followers {
+		sendDelta(c, g, event)
+	}
+
+	if len(lobby) > 0 {
+		view := g.View("")
+		message := newEncodedMessage(NewGameEnvelope(MessageGameUpdate, g, GameUpdatePayload{
+			Event:   event,
+			Game:    view,
+			Players: view.Players,
+		}))
+		for _, c := range lobby {
+			if data := message.bytes(c.format); data != nil {
+				c.enqueue(data)
+			}
+		}
+	}
+
+	if reaction := g.PendingReaction(); reaction != nil {
+		SendToUser(reaction.Player, NewGameEnvelope(MessagePrompt, g, PromptPayload{
+			Reaction: reaction,
+			Hand:     g.Hand(reaction.Player),
+		}))
+	}
 }
 
 func GetConnectedUsernames() []string {
@@ -0,0 +1,225 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/becaraya/katana-api/internal/game"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const testSecret = "test_secret"
+
+// receivedMessage est un message du serveur dont le payload reste encodé
+type receivedMessage struct {
+	Type    MessageType     `json:"type"`
+	GameID  string          `json:"game_id"`
+	Seq     int64           `json:"seq"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// newTestServer sert /ws avec les réglages donnés, complétés du secret de test
+func newTestServer(t *testing.T, config WebSocketConfig) string {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	config.TokenSecret = testSecret
+	if config.Tokens == nil {
+		config.Tokens = NewTokenStore()
+	}
+	router := gin.New()
+	router.GET("/ws", WebSocketHandler(config))
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+}
+
+// dial ouvre une connexion au nom de username et lit son message hello
+func dial(t *testing.T, url string, username string, roles ...string) *websocket.Conn {
+	t.Helper()
+	token, _, err := GenerateToken(username, false, roles, TokenKindAccess, testSecret, time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?token="+token, nil)
+	if err != nil {
+		t.Fatalf("dial as %s: %v", username, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	readUntil(t, conn, MessageHello)
+	return conn
+}
+
+// send écrit un message du client
+func send(t *testing.T, conn *websocket.Conn, messageType MessageType, payload interface{}) {
+	t.Helper()
+	data, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.WriteJSON(ClientEnvelope{Version: ProtocolVersion, Type: messageType, Payload: data}); err != nil {
+		t.Fatalf("send %s: %v", messageType, err)
+	}
+}
+
+// readUntil lit les messages jusqu'au premier du type attendu
+func readUntil(t *testing.T, conn *websocket.Conn, messageType MessageType) receivedMessage {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	defer conn.SetReadDeadline(time.Time{})
+	for {
+		var message receivedMessage
+		if err := conn.ReadJSON(&message); err != nil {
+			t.Fatalf("waiting for %s: %v", messageType, err)
+		}
+		if message.Type == messageType {
+			return message
+		}
+	}
+}
+
+// eventually attend qu'une condition devienne vraie
+func eventually(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting until %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// startedGame lance une partie entre des joueurs nommés prefix-1, prefix-2…,
+// abandonnée à la fin du test
+func startedGame(t *testing.T, prefix string) *game.Game {
+	t.Helper()
+	g, err := game.GetGameManager().CreateGame(prefix + "-1")
+	if err != nil {
+		t.Fatalf("CreateGame: %v", err)
+	}
+	for i := 1; i <= game.MinPlayers; i++ {
+		if err := g.AddPlayer(game.NewPlayer(prefix+"-"+strconv.Itoa(i), 0)); err != nil {
+			t.Fatalf("AddPlayer: %v", err)
+		}
+	}
+	if err := g.StartGame(); err != nil {
+		t.Fatalf("StartGame: %v", err)
+	}
+	t.Cleanup(func() { g.Abort(game.EndReasonModerator) })
+	return g
+}
+
+// player retourne la vue publique d'un joueur de la partie
+func player(g *game.Game, name string) *game.PlayerView {
+	for _, p := range g.View("").Players {
+		if p.Name == name {
+			return p
+		}
+	}
+	return &game.PlayerView{}
+}
+
+func TestReconnectOnUpgradeThenResume(t *testing.T) {
+	url := newTestServer(t, WebSocketConfig{ReconnectGrace: time.Minute})
+	g := startedGame(t, "reconnect")
+	name := "reconnect-1"
+
+	conn := dial(t, url, name)
+	lastSeq := g.LastSeq()
+	conn.Close()
+	eventually(t, "the seat is reserved", func() bool { return player(g, name).DisconnectedAt != nil })
+
+	// La nouvelle connexion rend le siège avant toute demande de reprise
+	conn = dial(t, url, name)
+	eventually(t, "the seat is given back", func() bool { return player(g, name).DisconnectedAt == nil })
+
+	send(t, conn, MessageResume, ResumePayload{GameID: g.ID, LastSeq: lastSeq})
+	var replay ReplayPayload
+	if err := json.Unmarshal(readUntil(t, conn, MessageReplay).Payload, &replay); err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for i, event := range replay.Events {
+		if event.Seq != lastSeq+int64(i)+1 {
+			t.Fatalf("replay is not contiguous from seq %d: %+v", lastSeq, replay.Events)
+		}
+		seen[event.Type] = true
+	}
+	if !seen[game.EventPlayerDisconnected] || !seen[game.EventPlayerReconnected] {
+		t.Fatalf("replay %+v misses the disconnection or the reconnection", replay.Events)
+	}
+	if len(replay.Hand) == 0 {
+		t.Fatal("replay does not send the hand back")
+	}
+	if snapshot := readUntil(t, conn, MessageSnapshot); snapshot.Seq != g.LastSeq() {
+		t.Fatalf("snapshot at seq %d, want %d", snapshot.Seq, g.LastSeq())
+	}
+}
+
+func TestKickedPlayerCannotResume(t *testing.T) {
+	url := newTestServer(t, WebSocketConfig{ReconnectGrace: time.Minute})
+	g := startedGame(t, "kicked")
+	name := "kicked-2"
+	if !g.Kick(name) {
+		t.Fatal("kick failed")
+	}
+
+	conn := dial(t, url, name)
+	send(t, conn, MessageResume, ResumePayload{GameID: g.ID})
+	var refused ErrorPayload
+	if err := json.Unmarshal(readUntil(t, conn, MessageError).Payload, &refused); err != nil {
+		t.Fatal(err)
+	}
+	if refused.Message != "kicked from this game" {
+		t.Fatalf("resume after a kick: got error %q", refused.Message)
+	}
+	if p := player(g, name); p.DisconnectedAt == nil || !p.Kicked {
+		t.Fatal("the kicked player got their seat back")
+	}
+}
+
+func TestSubscribeRequiresSeatOrModerator(t *testing.T) {
+	url := newTestServer(t, WebSocketConfig{})
+	g := startedGame(t, "subscribe")
+
+	outsider := dial(t, url, "subscribe-outsider")
+	send(t, outsider, MessageSubscribe, GameRefPayload{GameID: g.ID})
+	var refused ErrorPayload
+	if err := json.Unmarshal(readUntil(t, outsider, MessageError).Payload, &refused); err != nil {
+		t.Fatal(err)
+	}
+	if refused.Message != "not a player of this game, spectate it instead" {
+		t.Fatalf("outsider subscribe: got error %q", refused.Message)
+	}
+
+	for _, conn := range []*websocket.Conn{
+		dial(t, url, "subscribe-1"),
+		dial(t, url, "subscribe-moderator", RoleModerator),
+	} {
+		send(t, conn, MessageSubscribe, GameRefPayload{GameID: g.ID})
+		if subscribed := readUntil(t, conn, MessageSubscribed); subscribed.GameID != g.ID {
+			t.Fatalf("subscribed to %q, want %q", subscribed.GameID, g.ID)
+		}
+	}
+}
+
+func TestLegacyLobbyMessagesAreRejected(t *testing.T) {
+	url := newTestServer(t, WebSocketConfig{})
+	conn := dial(t, url, "legacy")
+
+	for _, messageType := range []MessageType{"join_game", "leave_game", "start_game"} {
+		send(t, conn, messageType, EmptyPayload{})
+		var refused ErrorPayload
+		if err := json.Unmarshal(readUntil(t, conn, MessageError).Payload, &refused); err != nil {
+			t.Fatal(err)
+		}
+		if refused.Message != "unknown message type: "+string(messageType) {
+			t.Fatalf("%s: got error %q", messageType, refused.Message)
+		}
+	}
+}
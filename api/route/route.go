@@ -6,24 +6,117 @@ import (
     "github.com/becaraya/katana-api/api/handler"
     "github.com/becaraya/katana-api/api/middleware"
     "github.com/becaraya/katana-api/internal/bootstrap"
+    "github.com/becaraya/katana-api/internal/chat"
+    "github.com/becaraya/katana-api/internal/game"
 
     "github.com/gin-gonic/gin"
 )
 
-func Setup(env *bootstrap.Env, timeout time.Duration, gin *gin.Engine) {
+func Setup(env *bootstrap.Env, timeout time.Duration, tokens *middleware.TokenStore, gin *gin.Engine) {
+    gameManager := game.GetGameManager()
+
+    rules := game.DefaultRuleset()
+    rules.SpectatorDelayEvents = env.SpectatorDelayEvents
+    gameManager.SetDefaultRules(rules)
+
+    // Diffuser les actions jouées par le serveur à l'expiration d'un délai
+    gameManager.OnTimeout(middleware.BroadcastGameUpdate)
+
+    // Registre partagé des tokens émis et révoqués
+    handler.SetTokenStore(tokens)
+
+    // Chat du salon d'accueil et des parties, supprimé avec sa partie
+    chatService := chat.NewService(chat.Config{
+        MaxLength:       env.ChatMaxLength,
+        RateLimit:       env.ChatRateLimit,
+        RateWindow:      time.Duration(env.ChatRateWindowSeconds) * time.Second,
+        EmoteRateLimit:  env.EmoteRateLimit,
+        EmoteRateWindow: time.Duration(env.EmoteRateWindowSeconds) * time.Second,
+        RecentSize:      env.ChatHistorySize,
+    })
+    chatService.SetFilter(chat.WordFilter(env.ChatBannedWords))
+    handler.SetChatService(chatService)
+    middleware.SetChatService(chatService)
+    gameManager.OnRemove(middleware.DropGameChat)
+
+    // Réglages communs au WebSocket et au flux SSE
+    streamConfig := middleware.WebSocketConfig{
+        TokenSecret:    env.AccessTokenSecret,
+        Tokens:         tokens,
+        ReconnectGrace: time.Duration(env.ReconnectGraceSeconds) * time.Second,
+        PingInterval:   time.Duration(env.WSPingIntervalSeconds) * time.Second,
+        PongWait:       time.Duration(env.WSPongWaitSeconds) * time.Second,
+        WriteWait:      time.Duration(env.WSWriteWaitSeconds) * time.Second,
+        MaxMessageSize: env.WSMaxMessageBytes,
+        AwayAfter:      time.Duration(env.WSAwayAfterSeconds) * time.Second,
+    }
+
     publicRouter := gin.Group("")
     {
+        publicRouter.POST("/register", handler.Register(env))
         publicRouter.POST("/login", handler.Login(env))
-        publicRouter.GET("/ws", middleware.WebSocketHandler)
+        publicRouter.POST("/login/guest", handler.GuestLogin(env))
+        publicRouter.POST("/auth/refresh", handler.Refresh(env))
+        publicRouter.GET("/ws", middleware.WebSocketHandler(streamConfig))
+        publicRouter.GET("/ws/schema", middleware.ProtocolSchemaHandler)
+        publicRouter.GET("/emotes", handler.ListEmotes)
     }
 
     protectedRouter := gin.Group("")
-    protectedRouter.Use(middleware.JWTAuthMiddleware(env.AccessTokenSecret))
+    protectedRouter.Use(middleware.JWTAuthMiddleware(env.AccessTokenSecret, tokens))
     {
-        protectedRouter.GET("/tokens", handler.ListTokens)
+        protectedRouter.POST("/auth/logout", handler.Logout)
         protectedRouter.GET("/game", handler.GetGameState)
         protectedRouter.POST("/game/join", handler.JoinGame)
         protectedRouter.POST("/game/leave", handler.LeaveGame)
         protectedRouter.POST("/game/start", handler.StartGame)
+        protectedRouter.GET("/game/bots", handler.ListBotKinds)
+        protectedRouter.POST("/game/bots", handler.AddBot)
+        protectedRouter.DELETE("/game/bots/:name", handler.RemoveBot)
+        protectedRouter.POST("/game/pause", handler.PauseGame)
+        protectedRouter.POST("/game/resume", handler.ResumeGame)
+        protectedRouter.POST("/game/command", handler.PlayCommand)
+        protectedRouter.GET("/game/hand", handler.GetHand)
+        protectedRouter.GET("/game/spectate", handler.SpectateGame)
+        protectedRouter.GET("/games/:id/events", middleware.EventStreamHandler(streamConfig))
+        protectedRouter.GET("/games/:id/chat", handler.GetGameChat)
+        protectedRouter.GET("/chat/mutes", handler.ListMutedUsers)
+        protectedRouter.PUT("/chat/mutes/:username", handler.MuteForMe)
+        protectedRouter.DELETE("/chat/mutes/:username", handler.UnmuteForMe)
+        protectedRouter.POST("/chat/reports", handler.ReportMessage)
+        protectedRouter.GET("/chat/emotes", handler.GetEmoteSettings)
+        protectedRouter.PUT("/chat/emotes/mute", handler.MuteEmotes)
+        protectedRouter.DELETE("/chat/emotes/mute", handler.UnmuteEmotes)
+    }
+
+    // Routes réservées aux joueurs qui ont un compte
+    accountRouter := gin.Group("")
+    accountRouter.Use(middleware.JWTAuthMiddleware(env.AccessTokenSecret, tokens), middleware.AccountRequiredMiddleware())
+    {
+        accountRouter.GET("/archive", handler.ListArchivedGames)
+        accountRouter.GET("/archive/:id", handler.GetArchivedGame)
+    }
+
+    // Routes de modération
+    moderatorRouter := gin.Group("")
+    moderatorRouter.Use(middleware.JWTAuthMiddleware(env.AccessTokenSecret, tokens), middleware.RequireRole(middleware.RoleModerator, middleware.RoleAdmin))
+    {
+        moderatorRouter.GET("/tokens", handler.ListTokens)
+        moderatorRouter.GET("/admin/sessions", handler.ListSessions)
+        moderatorRouter.POST("/admin/games/:id/end", handler.EndGame)
+        moderatorRouter.POST("/admin/users/:username/kick", handler.KickUser)
+        moderatorRouter.GET("/admin/chat/reports", handler.ListChatReports)
+        moderatorRouter.POST("/admin/users/:username/mute", handler.MuteUser)
+        moderatorRouter.DELETE("/admin/users/:username/mute", handler.UnmuteUser)
+    }
+
+    // Routes d'administration
+    adminRouter := gin.Group("")
+    adminRouter.Use(middleware.JWTAuthMiddleware(env.AccessTokenSecret, tokens), middleware.RequireRole(middleware.RoleAdmin))
+    {
+        adminRouter.POST("/admin/users/:username/ban", handler.BanUser)
+        adminRouter.DELETE("/admin/users/:username/ban", handler.UnbanUser)
+        adminRouter.PUT("/admin/users/:username/roles", handler.SetUserRoles)
+        adminRouter.GET("/admin/games/:id/hidden", handler.GetHiddenGameState)
     }
 }
\ No newline at end of file
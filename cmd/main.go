@@ -1,11 +1,22 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"github.com/becaraya/katana-api/api/handler"
+	"github.com/becaraya/katana-api/api/middleware"
 	route "github.com/becaraya/katana-api/api/route"
 
 	"github.com/becaraya/katana-api/internal/bootstrap"
+	"github.com/becaraya/katana-api/internal/game"
+	"github.com/becaraya/katana-api/internal/storage"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -19,18 +30,91 @@ func main() {
 
 	timeout := time.Duration(env.ContextTimeout) * time.Second
 
-	gin := gin.Default()
+	// Le logger de gin recopierait le token passé à /ws dans la requête
+	recovery := gin.Recovery()
+	gin := gin.New()
+	gin.Use(middleware.Logger(), recovery)
 
 	gin.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{env.FrontendUrl},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "Last-Event-ID"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
-	route.Setup(env, timeout, gin)
+	// Restaurer les tokens émis et révoqués avant d'accepter des requêtes
+	tokens := middleware.NewTokenStore()
+	var store *storage.BoltStore
+	if env.StoragePath != "" {
+		var err error
+		store, err = storage.OpenBolt(env.StoragePath)
+		if err != nil {
+			log.Fatal("Can't open the game storage: ", err)
+		}
+		defer store.Close()
 
-	gin.Run(env.ServerAddress)
+		if err := tokens.SetRepository(store.Tokens()); err != nil {
+			log.Fatal("Tokens can't be restored: ", err)
+		}
+	}
+
+	route.Setup(env, timeout, tokens, gin)
+
+	// Restaurer les parties sauvegardées sur disque
+	if store != nil {
+		handler.SetAccountStore(store.Accounts())
+
+		gameManager := game.GetGameManager()
+		gameManager.SetArchive(store.Archive())
+		if err := gameManager.SetRepository(store.Games()); err != nil {
+			log.Fatal("Games can't be restored: ", err)
+		}
+	}
+
+	// Rejouer les journaux pour retrouver les actions postérieures au dernier snapshot
+	if env.CommandLogDir != "" {
+		commandLog, err := storage.OpenCommandLog(env.CommandLogDir)
+		if err != nil {
+			log.Fatal("Can't open the command log: ", err)
+		}
+		defer commandLog.Close()
+
+		if err := game.GetGameManager().SetCommandLog(commandLog); err != nil {
+			log.Fatal("Command log can't be replayed: ", err)
+		}
+	}
+
+	// Arrêter le serveur et les tâches de fond sur SIGINT ou SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go game.GetGameManager().RunReaper(ctx, game.ReaperConfig{
+		Interval:      time.Duration(env.ReaperIntervalSeconds) * time.Second,
+		EmptyLobbyTTL: time.Duration(env.EmptyLobbyTTLMinutes) * time.Minute,
+		IdleGameTTL:   time.Duration(env.IdleGameTTLMinutes) * time.Minute,
+		EndedGameTTL:  time.Duration(env.EndedGameTTLMinutes) * time.Minute,
+	})
+
+	server := &http.Server{
+		Addr:    env.ServerAddress,
+		Handler: gin,
+	}
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Server error: ", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("Shutting down server...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Println("Server forced to shutdown: ", err)
+	}
 }
@@ -0,0 +1,25 @@
+// Commande schema : écrit le JSON Schema du protocole WebSocket généré à
+// partir des types de api/middleware
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/becaraya/katana-api/api/middleware"
+)
+
+func main() {
+	output := flag.String("o", "docs/websocket.schema.json", "fichier de sortie")
+	flag.Parse()
+
+	data, err := json.MarshalIndent(middleware.ProtocolSchema(), "", "  ")
+	if err != nil {
+		log.Fatal("Schema can't be generated: ", err)
+	}
+	if err := os.WriteFile(*output, append(data, '\n'), 0o644); err != nil {
+		log.Fatal("Schema can't be written: ", err)
+	}
+}
@@ -0,0 +1,321 @@
+// Commande simulate : joue des milliers de parties entre bots, sans HTTP, et
+// écrit les taux de victoire par rôle, personnage, rang de jeu et sorte de
+// bot pour vérifier l'équilibre du jeu
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/becaraya/katana-api/internal/game"
+)
+
+// tally compte les sièges d'une catégorie et leurs victoires
+type tally struct {
+	Name    string  `json:"name"`
+	Seats   int     `json:"seats"`
+	Wins    int     `json:"wins"`
+	WinRate float64 `json:"win_rate"`
+}
+
+// report agrège les résultats de toutes les parties ; les taux de victoire ne
+// comptent que les parties terminées
+type report struct {
+	Games              int      `json:"games"`
+	Unfinished         int      `json:"unfinished"`
+	AverageTurns       float64  `json:"average_turns"`
+	AverageCommands    float64  `json:"average_commands"`
+	ExhaustionRate     float64  `json:"exhaustion_rate"`
+	AverageExhaustions float64  `json:"average_exhaustions"`
+	Teams              []*tally `json:"teams"`
+	Roles              []*tally `json:"roles"`
+	Characters         []*tally `json:"characters"`
+	Seats              []*tally `json:"seats"`
+	Bots               []*tally `json:"bots"`
+}
+
+func main() {
+	games := flag.Int("games", 1000, "nombre de parties")
+	players := flag.String("players", "3-7", "nombres de joueurs, par exemple 5 ou 3-7 ou 4,6")
+	bots := flag.String("bots", game.BotNormal, "sortes de bots des sièges, répétées si la liste est plus courte")
+	characters := flag.String("characters", "", "personnages imposés à chaque partie, par nom ou identifiant")
+	seed := flag.Int64("seed", 1, "graine de la première partie, les suivantes prennent les graines suivantes")
+	maxCommands := flag.Int("max-commands", game.DefaultMaxCommands, "commandes au-delà desquelles une partie est abandonnée")
+	workers := flag.Int("workers", runtime.NumCPU(), "parties jouées en parallèle")
+	format := flag.String("format", "csv", "format de sortie : csv ou json")
+	output := flag.String("o", "", "fichier de sortie, la sortie standard par défaut")
+	flag.Parse()
+
+	counts, err := parsePlayers(*players)
+	if err != nil {
+		log.Fatal("Invalid -players: ", err)
+	}
+	ids, err := parseCharacters(*characters)
+	if err != nil {
+		log.Fatal("Invalid -characters: ", err)
+	}
+	configs := make([]game.SimulationConfig, *games)
+	for i := range configs {
+		configs[i] = game.SimulationConfig{
+			Players:     counts[i%len(counts)],
+			Bots:        splitList(*bots),
+			Characters:  ids,
+			Seed:        *seed + int64(i),
+			MaxCommands: *maxCommands,
+		}
+	}
+
+	results, err := simulate(configs, *workers)
+	if err != nil {
+		log.Fatal("Simulation failed: ", err)
+	}
+
+	out := io.Writer(os.Stdout)
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			log.Fatal("Output can't be created: ", err)
+		}
+		defer file.Close()
+		out = file
+	}
+	switch *format {
+	case "json":
+		err = writeJSON(out, aggregate(results))
+	case "csv":
+		err = writeCSV(out, aggregate(results))
+	default:
+		log.Fatal("Unknown -format: ", *format)
+	}
+	if err != nil {
+		log.Fatal("Report can't be written: ", err)
+	}
+}
+
+// simulate joue les parties sur plusieurs goroutines en gardant l'ordre des
+// graines, pour qu'un même appel produise toujours le même rapport
+func simulate(configs []game.SimulationConfig, workers int) ([]*game.SimulationResult, error) {
+	if workers < 1 {
+		workers = 1
+	}
+	results := make([]*game.SimulationResult, len(configs))
+	errs := make([]error, len(configs))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i], errs[i] = game.Simulate(configs[i])
+			}
+		}()
+	}
+	for i := range configs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("seed %d: %w", configs[i].Seed, err)
+		}
+	}
+	return results, nil
+}
+
+// aggregate calcule les statistiques de l'ensemble des parties
+func aggregate(results []*game.SimulationResult) *report {
+	r := &report{Games: len(results)}
+	teams := make(map[string]*tally)
+	roles := make(map[string]*tally)
+	characters := make(map[string]*tally)
+	seats := make(map[string]*tally)
+	bots := make(map[string]*tally)
+	exhausted := 0
+	for _, result := range results {
+		r.AverageTurns += float64(result.Turns)
+		r.AverageCommands += float64(result.Commands)
+		r.AverageExhaustions += float64(result.Exhaustions)
+		if result.Exhaustions > 0 {
+			exhausted++
+		}
+		if !result.Ended {
+			r.Unfinished++
+			continue
+		}
+
+		present := make(map[game.Team]bool)
+		for _, seat := range result.Seats {
+			count(roles, string(seat.Role), seat.Won)
+			count(characters, seat.Character, seat.Won)
+			count(seats, strconv.Itoa(seat.Order), seat.Won)
+			count(bots, seat.Bot, seat.Won)
+			present[seat.Team] = true
+		}
+		// Une équipe compte une fois par partie où elle est présente
+		for team := range present {
+			count(teams, string(team), team == result.Winner)
+		}
+	}
+	if r.Games > 0 {
+		games := float64(r.Games)
+		r.AverageTurns /= games
+		r.AverageCommands /= games
+		r.AverageExhaustions /= games
+		r.ExhaustionRate = float64(exhausted) / games
+	}
+	r.Teams = sorted(teams)
+	r.Roles = sorted(roles)
+	r.Characters = sorted(characters)
+	r.Seats = sorted(seats)
+	r.Bots = sorted(bots)
+	return r
+}
+
+func count(tallies map[string]*tally, name string, won bool) {
+	t, exists := tallies[name]
+	if !exists {
+		t = &tally{Name: name}
+		tallies[name] = t
+	}
+	t.Seats++
+	if won {
+		t.Wins++
+	}
+	t.WinRate = float64(t.Wins) / float64(t.Seats)
+}
+
+// sorted retourne les catégories par nom, les rangs de jeu dans l'ordre numérique
+func sorted(tallies map[string]*tally) []*tally {
+	list := make([]*tally, 0, len(tallies))
+	for _, t := range tallies {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, errA := strconv.Atoi(list[i].Name)
+		b, errB := strconv.Atoi(list[j].Name)
+		if errA == nil && errB == nil {
+			return a < b
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+func writeJSON(out io.Writer, r *report) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// writeCSV écrit une ligne par statistique : les résumés de partie dans la
+// catégorie game, puis une ligne par équipe, rôle, personnage, rang et bot
+func writeCSV(out io.Writer, r *report) error {
+	w := csv.NewWriter(out)
+	number := func(value float64) string { return strconv.FormatFloat(value, 'f', 4, 64) }
+	rows := [][]string{
+		{"category", "name", "count", "wins", "rate", "average"},
+		{"game", "games", strconv.Itoa(r.Games), "", "", ""},
+		{"game", "unfinished", strconv.Itoa(r.Unfinished), "", "", ""},
+		{"game", "turns", "", "", "", number(r.AverageTurns)},
+		{"game", "commands", "", "", "", number(r.AverageCommands)},
+		{"game", "exhaustions", "", "", number(r.ExhaustionRate), number(r.AverageExhaustions)},
+	}
+	for _, group := range []struct {
+		category string
+		tallies  []*tally
+	}{
+		{"team", r.Teams},
+		{"role", r.Roles},
+		{"character", r.Characters},
+		{"seat", r.Seats},
+		{"bot", r.Bots},
+	} {
+		for _, t := range group.tallies {
+			rows = append(rows, []string{group.category, t.Name, strconv.Itoa(t.Seats), strconv.Itoa(t.Wins), number(t.WinRate), ""})
+		}
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return err
+	}
+	return w.Error()
+}
+
+// parsePlayers lit une liste de nombres de joueurs et d'intervalles
+func parsePlayers(value string) ([]int, error) {
+	var counts []int
+	for _, part := range splitList(value) {
+		from, to, isRange := strings.Cut(part, "-")
+		low, err := strconv.Atoi(from)
+		if err != nil {
+			return nil, err
+		}
+		high := low
+		if isRange {
+			if high, err = strconv.Atoi(to); err != nil {
+				return nil, err
+			}
+		}
+		for n := low; n <= high; n++ {
+			counts = append(counts, n)
+		}
+	}
+	if len(counts) == 0 {
+		return nil, fmt.Errorf("no player count in %q", value)
+	}
+	return counts, nil
+}
+
+// parseCharacters lit une liste de personnages par identifiant ou par nom,
+// sans tenir compte de la casse ni des accents
+func parseCharacters(value string) ([]int, error) {
+	var ids []int
+	seen := make(map[int]bool)
+	for _, part := range splitList(value) {
+		id, err := strconv.Atoi(part)
+		if err != nil {
+			id = 0
+			for _, character := range game.Characters {
+				if strings.EqualFold(plain(character.Name), plain(part)) {
+					id = character.ID
+				}
+			}
+		}
+		if id < 1 || id > len(game.Characters) {
+			return nil, fmt.Errorf("unknown character %q", part)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("character %q listed twice", part)
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// plain retire les accents des noms de personnages, Hanzõ et Kojirõ
+func plain(name string) string {
+	return strings.ReplaceAll(name, "õ", "o")
+}
+
+func splitList(value string) []string {
+	var parts []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
@@ -0,0 +1,147 @@
+package account
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// GuestPrefix préfixe les noms des joueurs connectés sans compte
+const GuestPrefix = "guest-"
+
+// MinPasswordLength est la longueur minimale d'un mot de passe
+const MinPasswordLength = 8
+
+var (
+	ErrUsernameTaken      = errors.New("username is already taken")
+	ErrInvalidUsername    = errors.New("username must be 3 to 20 letters, digits, '-' or '_' and cannot start with " + GuestPrefix)
+	ErrPasswordTooShort   = errors.New("password is too short")
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrAccountNotFound    = errors.New("account not found")
+	ErrAccountBanned      = errors.New("account is banned")
+)
+
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{3,20}$`)
+
+// Account représente un compte joueur
+type Account struct {
+	Username     string    `json:"username"`
+	PasswordHash []byte    `json:"password_hash"`
+	CreatedAt    time.Time `json:"created_at"`
+	Roles        []string  `json:"roles,omitempty"`
+	Banned       bool      `json:"banned,omitempty"`
+}
+
+// Store conserve les comptes, un nom d'utilisateur ne peut être pris qu'une fois.
+// Update applique change à une copie du compte et l'enregistre de façon atomique.
+type Store interface {
+	Create(account *Account) error
+	Get(username string) (*Account, bool)
+	Update(username string, change func(account *Account) error) error
+}
+
+// New crée un compte avec un mot de passe haché
+func New(username, password string) (*Account, error) {
+	if err := ValidateUsername(username); err != nil {
+		return nil, err
+	}
+	if len(password) < MinPasswordLength {
+		return nil, ErrPasswordTooShort
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	return &Account{
+		Username:     username,
+		PasswordHash: hash,
+		CreatedAt:    time.Now(),
+	}, nil
+}
+
+// ValidateUsername vérifie qu'un nom peut être utilisé pour un compte
+func ValidateUsername(username string) error {
+	if !usernamePattern.MatchString(username) || IsGuest(username) {
+		return ErrInvalidUsername
+	}
+	return nil
+}
+
+// IsGuest indique si un nom appartient à un invité
+func IsGuest(username string) bool {
+	return strings.HasPrefix(strings.ToLower(username), GuestPrefix)
+}
+
+// Authenticate vérifie le mot de passe d'un compte
+func Authenticate(store Store, username, password string) (*Account, error) {
+	account, exists := store.Get(username)
+	if !exists {
+		// Hacher quand même pour ne pas révéler l'existence du compte par le temps de réponse
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword(account.PasswordHash, []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	if account.Banned {
+		return nil, ErrAccountBanned
+	}
+	return account, nil
+}
+
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("katana-dummy-password"), bcrypt.DefaultCost)
+
+// MemoryStore conserve les comptes en mémoire
+type MemoryStore struct {
+	accounts map[string]*Account
+	mu       sync.RWMutex
+}
+
+// NewMemoryStore crée un stockage de comptes en mémoire
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		accounts: make(map[string]*Account),
+	}
+}
+
+func (s *MemoryStore) Create(account *Account) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := strings.ToLower(account.Username)
+	if _, exists := s.accounts[key]; exists {
+		return ErrUsernameTaken
+	}
+	s.accounts[key] = account
+	return nil
+}
+
+func (s *MemoryStore) Get(username string) (*Account, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	account, exists := s.accounts[strings.ToLower(username)]
+	return account, exists
+}
+
+func (s *MemoryStore) Update(username string, change func(account *Account) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := strings.ToLower(username)
+	current, exists := s.accounts[key]
+	if !exists {
+		return ErrAccountNotFound
+	}
+	updated := *current
+	updated.Roles = append([]string(nil), current.Roles...)
+	if err := change(&updated); err != nil {
+		return err
+	}
+	s.accounts[key] = &updated
+	return nil
+}
@@ -0,0 +1,20 @@
+package account
+
+import "time"
+
+// TokenInfo décrit un token émis, identifié par son jti
+type TokenInfo struct {
+	ID        string    `json:"id"`
+	Session   string    `json:"session"`
+	Username  string    `json:"username"`
+	Kind      string    `json:"kind"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// TokenRepository conserve les tokens émis et révoqués pour qu'un
+// redémarrage ne perde ni les sessions ni les révocations
+type TokenRepository interface {
+	Save(info TokenInfo, revoked bool) error
+	Delete(id string) error
+	LoadAll() (issued []TokenInfo, revoked []TokenInfo, err error)
+}
@@ -7,18 +7,58 @@ import (
 )
 
 type Env struct {
-	AppEnv                string `mapstructure:"APP_ENV"`
-	ServerAddress         string `mapstructure:"SERVER_ADDRESS"`
-	ContextTimeout        int    `mapstructure:"CONTEXT_TIMEOUT"`
-	AccessTokenExpiryHour int    `mapstructure:"ACCESS_TOKEN_EXPIRY_HOUR"`
-	AccessTokenSecret     string `mapstructure:"ACCESS_TOKEN_SECRET"`
-	RefreshTokenSecret    string `mapstructure:"REFRESH_TOKEN_SECRET"`
-	FrontendUrl           string `mapstructure:"FRONTEND_URL"`
+	AppEnv                 string   `mapstructure:"APP_ENV"`
+	ServerAddress          string   `mapstructure:"SERVER_ADDRESS"`
+	ContextTimeout         int      `mapstructure:"CONTEXT_TIMEOUT"`
+	AccessTokenExpiryHour  int      `mapstructure:"ACCESS_TOKEN_EXPIRY_HOUR"`
+	AccessTokenSecret      string   `mapstructure:"ACCESS_TOKEN_SECRET"`
+	RefreshTokenSecret     string   `mapstructure:"REFRESH_TOKEN_SECRET"`
+	RefreshTokenExpiryHour int      `mapstructure:"REFRESH_TOKEN_EXPIRY_HOUR"`
+	FrontendUrl            string   `mapstructure:"FRONTEND_URL"`
+	ReconnectGraceSeconds  int      `mapstructure:"RECONNECT_GRACE_SECONDS"`
+	ReaperIntervalSeconds  int      `mapstructure:"REAPER_INTERVAL_SECONDS"`
+	EmptyLobbyTTLMinutes   int      `mapstructure:"EMPTY_LOBBY_TTL_MINUTES"`
+	IdleGameTTLMinutes     int      `mapstructure:"IDLE_GAME_TTL_MINUTES"`
+	EndedGameTTLMinutes    int      `mapstructure:"ENDED_GAME_TTL_MINUTES"`
+	SpectatorDelayEvents   int      `mapstructure:"SPECTATOR_DELAY_EVENTS"`
+	StoragePath            string   `mapstructure:"STORAGE_PATH"`
+	CommandLogDir          string   `mapstructure:"COMMAND_LOG_DIR"`
+	AdminUsernames         []string `mapstructure:"ADMIN_USERNAMES"`
+	WSPingIntervalSeconds  int      `mapstructure:"WS_PING_INTERVAL_SECONDS"`
+	WSPongWaitSeconds      int      `mapstructure:"WS_PONG_WAIT_SECONDS"`
+	WSWriteWaitSeconds     int      `mapstructure:"WS_WRITE_WAIT_SECONDS"`
+	WSMaxMessageBytes      int64    `mapstructure:"WS_MAX_MESSAGE_BYTES"`
+	WSAwayAfterSeconds     int      `mapstructure:"WS_AWAY_AFTER_SECONDS"`
+	ChatMaxLength          int      `mapstructure:"CHAT_MAX_LENGTH"`
+	ChatRateLimit          int      `mapstructure:"CHAT_RATE_LIMIT"`
+	ChatRateWindowSeconds  int      `mapstructure:"CHAT_RATE_WINDOW_SECONDS"`
+	ChatHistorySize        int      `mapstructure:"CHAT_HISTORY_SIZE"`
+	ChatBannedWords        []string `mapstructure:"CHAT_BANNED_WORDS"`
+	EmoteRateLimit         int      `mapstructure:"EMOTE_RATE_LIMIT"`
+	EmoteRateWindowSeconds int      `mapstructure:"EMOTE_RATE_WINDOW_SECONDS"`
 }
 
 func NewEnv() *Env {
 	env := Env{}
 	viper.SetConfigFile(".env")
+	viper.SetDefault("ACCESS_TOKEN_EXPIRY_HOUR", 2)
+	viper.SetDefault("REFRESH_TOKEN_EXPIRY_HOUR", 168)
+	viper.SetDefault("RECONNECT_GRACE_SECONDS", 120)
+	viper.SetDefault("REAPER_INTERVAL_SECONDS", 60)
+	viper.SetDefault("EMPTY_LOBBY_TTL_MINUTES", 30)
+	viper.SetDefault("IDLE_GAME_TTL_MINUTES", 120)
+	viper.SetDefault("ENDED_GAME_TTL_MINUTES", 15)
+	viper.SetDefault("WS_PING_INTERVAL_SECONDS", 50)
+	viper.SetDefault("WS_PONG_WAIT_SECONDS", 60)
+	viper.SetDefault("WS_WRITE_WAIT_SECONDS", 10)
+	viper.SetDefault("WS_MAX_MESSAGE_BYTES", 8192)
+	viper.SetDefault("WS_AWAY_AFTER_SECONDS", 300)
+	viper.SetDefault("CHAT_MAX_LENGTH", 280)
+	viper.SetDefault("CHAT_RATE_LIMIT", 5)
+	viper.SetDefault("CHAT_RATE_WINDOW_SECONDS", 10)
+	viper.SetDefault("CHAT_HISTORY_SIZE", 50)
+	viper.SetDefault("EMOTE_RATE_LIMIT", 3)
+	viper.SetDefault("EMOTE_RATE_WINDOW_SECONDS", 5)
 
 	err := viper.ReadInConfig()
 	if err != nil {
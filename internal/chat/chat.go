@@ -0,0 +1,313 @@
+package chat
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	ErrEmptyMessage    = errors.New("message is empty")
+	ErrMessageTooLong  = errors.New("message is too long")
+	ErrRateLimited     = errors.New("too many messages, slow down")
+	ErrMuted           = errors.New("you are muted")
+	ErrMessageRejected = errors.New("message rejected by the filter")
+	ErrMessageNotFound = errors.New("message not found")
+	ErrUnknownEmote    = errors.New("unknown emote")
+)
+
+// maxChannelHistory borne l'historique conservé d'un canal
+const maxChannelHistory = 1000
+
+// Message est un message de chat d'un canal : le salon d'accueil ou une partie
+type Message struct {
+	ID      int64     `json:"id"`
+	Channel string    `json:"channel"`
+	From    string    `json:"from"`
+	Text    string    `json:"text"`
+	At      time.Time `json:"at"`
+}
+
+// Report est le signalement d'un message à la modération
+type Report struct {
+	Message    Message   `json:"message"`
+	ReportedBy string    `json:"reported_by"`
+	Reason     string    `json:"reason,omitempty"`
+	At         time.Time `json:"at"`
+}
+
+// Filter est appelé sur chaque message avant sa publication : il retourne le
+// texte à publier, éventuellement censuré, ou une erreur pour le refuser
+type Filter func(text string) (string, error)
+
+// Config regroupe les limites du chat. RateLimit messages au plus sont
+// acceptés par utilisateur sur chaque fenêtre RateWindow, et EmoteRateLimit
+// emotes sur chaque fenêtre EmoteRateWindow ; RecentSize est le nombre de
+// messages renvoyés à ceux qui rejoignent un canal.
+type Config struct {
+	MaxLength       int
+	RateLimit       int
+	RateWindow      time.Duration
+	EmoteRateLimit  int
+	EmoteRateWindow time.Duration
+	RecentSize      int
+}
+
+// DefaultConfig retourne les limites par défaut du chat
+func DefaultConfig() Config {
+	return Config{
+		MaxLength:       280,
+		RateLimit:       5,
+		RateWindow:      10 * time.Second,
+		EmoteRateLimit:  3,
+		EmoteRateWindow: 5 * time.Second,
+		RecentSize:      50,
+	}
+}
+
+// Service conserve les canaux de chat, les sourdines et les signalements
+type Service struct {
+	mu          sync.Mutex
+	config      Config
+	filter      Filter
+	nextID      int64
+	channels    map[string][]Message
+	sent        map[string][]time.Time
+	emotesSent  map[string][]time.Time
+	muted       map[string]time.Time
+	ignored     map[string]map[string]bool
+	emotesMuted map[string]bool
+	reports     []Report
+}
+
+// NewService crée un service de chat sans filtre
+func NewService(config Config) *Service {
+	defaults := DefaultConfig()
+	if config.MaxLength <= 0 {
+		config.MaxLength = defaults.MaxLength
+	}
+	if config.RateWindow <= 0 {
+		config.RateWindow = defaults.RateWindow
+	}
+	if config.EmoteRateWindow <= 0 {
+		config.EmoteRateWindow = defaults.EmoteRateWindow
+	}
+	if config.RecentSize <= 0 {
+		config.RecentSize = defaults.RecentSize
+	}
+	return &Service{
+		config:      config,
+		channels:    make(map[string][]Message),
+		sent:        make(map[string][]time.Time),
+		emotesSent:  make(map[string][]time.Time),
+		muted:       make(map[string]time.Time),
+		ignored:     make(map[string]map[string]bool),
+		emotesMuted: make(map[string]bool),
+	}
+}
+
+// SetFilter remplace le filtre appliqué aux messages, nil le désactive
+func (s *Service) SetFilter(filter Filter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.filter = filter
+}
+
+// Post publie un message dans un canal après avoir vérifié sa longueur, la
+// sourdine et le débit de son auteur, puis l'avoir passé au filtre
+func (s *Service) Post(channel, from, text string) (Message, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return Message{}, ErrEmptyMessage
+	}
+	if utf8.RuneCountInString(text) > s.config.MaxLength {
+		return Message{}, ErrMessageTooLong
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.isMuted(from, now) {
+		return Message{}, ErrMuted
+	}
+	if !allow(s.sent, from, now, s.config.RateLimit, s.config.RateWindow) {
+		return Message{}, ErrRateLimited
+	}
+	if s.filter != nil {
+		filtered, err := s.filter(text)
+		if err != nil {
+			return Message{}, ErrMessageRejected
+		}
+		text = filtered
+	}
+
+	s.nextID++
+	message := Message{ID: s.nextID, Channel: channel, From: from, Text: text, At: now}
+	messages := append(s.channels[channel], message)
+	if len(messages) > maxChannelHistory {
+		messages = messages[len(messages)-maxChannelHistory:]
+	}
+	s.channels[channel] = messages
+	return message, nil
+}
+
+// isMuted indique si un utilisateur est privé de parole, le verrou doit être tenu
+func (s *Service) isMuted(username string, now time.Time) bool {
+	until, ok := s.muted[username]
+	if !ok {
+		return false
+	}
+	if now.Before(until) {
+		return true
+	}
+	delete(s.muted, username)
+	return false
+}
+
+// allow enregistre un envoi dans sent s'il reste dans la limite de débit, le
+// verrou doit être tenu
+func allow(sent map[string][]time.Time, from string, now time.Time, limit int, window time.Duration) bool {
+	if limit <= 0 {
+		return true
+	}
+	recent := sent[from][:0]
+	for _, at := range sent[from] {
+		if now.Sub(at) < window {
+			recent = append(recent, at)
+		}
+	}
+	if len(recent) >= limit {
+		sent[from] = recent
+		return false
+	}
+	sent[from] = append(recent, now)
+	return true
+}
+
+// Recent retourne les derniers messages d'un canal, à envoyer à ceux qui le rejoignent
+func (s *Service) Recent(channel string) []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	messages := s.channels[channel]
+	if len(messages) > s.config.RecentSize {
+		messages = messages[len(messages)-s.config.RecentSize:]
+	}
+	return append([]Message{}, messages...)
+}
+
+// History retourne tout l'historique conservé d'un canal
+func (s *Service) History(channel string) []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message{}, s.channels[channel]...)
+}
+
+// DropChannel supprime l'historique d'un canal, à la fin de vie de sa partie
+func (s *Service) DropChannel(channel string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.channels, channel)
+}
+
+// Mute empêche un utilisateur d'écrire jusqu'à until
+func (s *Service) Mute(username string, until time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.muted[username] = until
+}
+
+// Unmute rend la parole à un utilisateur
+func (s *Service) Unmute(username string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.muted, username)
+}
+
+// Ignore masque à viewer les messages d'un autre utilisateur
+func (s *Service) Ignore(viewer, username string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ignored[viewer] == nil {
+		s.ignored[viewer] = make(map[string]bool)
+	}
+	s.ignored[viewer][username] = true
+}
+
+// Unignore affiche de nouveau à viewer les messages d'un utilisateur
+func (s *Service) Unignore(viewer, username string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.ignored[viewer], username)
+}
+
+// Ignored retourne les utilisateurs dont viewer masque les messages
+func (s *Service) Ignored(viewer string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	usernames := make([]string, 0, len(s.ignored[viewer]))
+	for username := range s.ignored[viewer] {
+		usernames = append(usernames, username)
+	}
+	return usernames
+}
+
+// Ignores indique si viewer masque les messages d'un utilisateur
+func (s *Service) Ignores(viewer, username string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ignored[viewer][username]
+}
+
+// Report signale un message d'un canal à la modération
+func (s *Service) Report(channel string, id int64, reportedBy, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, message := range s.channels[channel] {
+		if message.ID == id {
+			s.reports = append(s.reports, Report{
+				Message:    message,
+				ReportedBy: reportedBy,
+				Reason:     reason,
+				At:         time.Now(),
+			})
+			return nil
+		}
+	}
+	return ErrMessageNotFound
+}
+
+// Reports retourne les signalements reçus
+func (s *Service) Reports() []Report {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Report{}, s.reports...)
+}
+
+// WordFilter retourne un filtre qui masque les mots interdits, sans tenir
+// compte de la casse
+func WordFilter(words []string) Filter {
+	quoted := make([]string, 0, len(words))
+	for _, word := range words {
+		if word = strings.TrimSpace(word); word != "" {
+			quoted = append(quoted, regexp.QuoteMeta(word))
+		}
+	}
+	if len(quoted) == 0 {
+		return nil
+	}
+
+	pattern := regexp.MustCompile(`(?i)\b(` + strings.Join(quoted, "|") + `)\b`)
+	return func(text string) (string, error) {
+		return pattern.ReplaceAllStringFunc(text, func(word string) string {
+			return strings.Repeat("*", utf8.RuneCountInString(word))
+		}), nil
+	}
+}
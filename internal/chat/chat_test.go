@@ -0,0 +1,103 @@
+package chat
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimitPerUser(t *testing.T) {
+	s := NewService(Config{RateLimit: 2, RateWindow: time.Hour})
+	for i := 0; i < 2; i++ {
+		if _, err := s.Post("lobby", "alice", "hello"); err != nil {
+			t.Fatalf("message %d: %v", i+1, err)
+		}
+	}
+	if _, err := s.Post("lobby", "alice", "hello"); err != ErrRateLimited {
+		t.Fatalf("third message in the window: got %v, want %v", err, ErrRateLimited)
+	}
+	if _, err := s.Post("other", "alice", "hello"); err != ErrRateLimited {
+		t.Fatalf("the limit is per user, not per channel: got %v", err)
+	}
+	if _, err := s.Post("lobby", "bob", "hello"); err != nil {
+		t.Fatalf("another user is limited too: %v", err)
+	}
+	if got := len(s.History("lobby")); got != 3 {
+		t.Fatalf("lobby holds %d messages, want 3", got)
+	}
+
+	// Les envois sortis de la fenêtre ne comptent plus
+	s = NewService(Config{RateLimit: 1, RateWindow: 20 * time.Millisecond})
+	s.Post("lobby", "alice", "hello")
+	time.Sleep(30 * time.Millisecond)
+	if _, err := s.Post("lobby", "alice", "hello again"); err != nil {
+		t.Fatalf("message after the window: %v", err)
+	}
+}
+
+func TestMute(t *testing.T) {
+	s := NewService(DefaultConfig())
+	s.Mute("alice", time.Now().Add(time.Hour))
+	if _, err := s.Post("lobby", "alice", "hello"); err != ErrMuted {
+		t.Fatalf("muted user posts: got %v, want %v", err, ErrMuted)
+	}
+	if _, err := s.SendEmote("alice", Emotes[0].ID); err != ErrMuted {
+		t.Fatalf("muted user sends an emote: got %v, want %v", err, ErrMuted)
+	}
+	s.Unmute("alice")
+	if _, err := s.Post("lobby", "alice", "hello"); err != nil {
+		t.Fatalf("unmuted user: %v", err)
+	}
+
+	// Une sourdine expirée tombe d'elle-même
+	s.Mute("bob", time.Now().Add(-time.Second))
+	if _, err := s.Post("lobby", "bob", "hello"); err != nil {
+		t.Fatalf("expired mute: %v", err)
+	}
+}
+
+func TestFilterHook(t *testing.T) {
+	s := NewService(DefaultConfig())
+	s.SetFilter(func(text string) (string, error) {
+		if text == "spam" {
+			return "", errors.New("spam")
+		}
+		return "[" + text + "]", nil
+	})
+	if _, err := s.Post("lobby", "alice", "spam"); err != ErrMessageRejected {
+		t.Fatalf("rejected message: got %v, want %v", err, ErrMessageRejected)
+	}
+	message, err := s.Post("lobby", "alice", "  hi  ")
+	if err != nil || message.Text != "[hi]" {
+		t.Fatalf("filtered message: got %q, %v, want the trimmed text rewritten by the filter", message.Text, err)
+	}
+	if history := s.History("lobby"); len(history) != 1 || history[0].Text != "[hi]" {
+		t.Fatalf("history holds %+v, want only the filtered message", history)
+	}
+
+	s.SetFilter(WordFilter([]string{"baka", " "}))
+	if message, _ := s.Post("lobby", "alice", "BAKA, not bakabon"); message.Text != "****, not bakabon" {
+		t.Fatalf("word filter gives %q", message.Text)
+	}
+	s.SetFilter(nil)
+	if message, _ := s.Post("lobby", "alice", "baka"); message.Text != "baka" {
+		t.Fatalf("removed filter still rewrites into %q", message.Text)
+	}
+	if WordFilter([]string{" ", ""}) != nil {
+		t.Fatal("a filter without words is not nil")
+	}
+}
+
+func TestReportNeedsAnExistingMessage(t *testing.T) {
+	s := NewService(DefaultConfig())
+	message, _ := s.Post("game", "alice", "hello")
+	if err := s.Report("other", message.ID, "bob", "rude"); err != ErrMessageNotFound {
+		t.Fatalf("report in the wrong channel: got %v, want %v", err, ErrMessageNotFound)
+	}
+	if err := s.Report("game", message.ID, "bob", "rude"); err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	if reports := s.Reports(); len(reports) != 1 || reports[0].Message != message || reports[0].ReportedBy != "bob" {
+		t.Fatalf("reports hold %+v", reports)
+	}
+}
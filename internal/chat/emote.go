@@ -0,0 +1,79 @@
+package chat
+
+import "time"
+
+// Emote est une réaction rapide du catalogue
+type Emote struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+}
+
+// Emotes est le catalogue des emotes, dans l'ordre d'affichage
+var Emotes = []Emote{
+	{ID: "bow", Label: "*s'incline*"},
+	{ID: "laugh", Label: "Ha ha ha !"},
+	{ID: "shogun", Label: "Je suis le Shogun !"},
+	{ID: "ouch", Label: "Aïe !"},
+	{ID: "think", Label: "Hmm..."},
+	{ID: "well_played", Label: "Bien joué !"},
+	{ID: "sorry", Label: "Désolé !"},
+	{ID: "thanks", Label: "Merci !"},
+}
+
+// EmoteByID retourne une emote du catalogue
+func EmoteByID(id string) (Emote, bool) {
+	for _, emote := range Emotes {
+		if emote.ID == id {
+			return emote, true
+		}
+	}
+	return Emote{}, false
+}
+
+// SendEmote vérifie qu'un utilisateur peut envoyer une emote du catalogue :
+// il ne doit pas être privé de parole ni dépasser la limite de débit des emotes
+func (s *Service) SendEmote(from, id string) (Emote, error) {
+	emote, ok := EmoteByID(id)
+	if !ok {
+		return Emote{}, ErrUnknownEmote
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.isMuted(from, now) {
+		return Emote{}, ErrMuted
+	}
+	if !allow(s.emotesSent, from, now, s.config.EmoteRateLimit, s.config.EmoteRateWindow) {
+		return Emote{}, ErrRateLimited
+	}
+	return emote, nil
+}
+
+// MuteEmotes masque ou affiche toutes les emotes à viewer
+func (s *Service) MuteEmotes(viewer string, muted bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if muted {
+		s.emotesMuted[viewer] = true
+	} else {
+		delete(s.emotesMuted, viewer)
+	}
+}
+
+// ShowsEmote indique si viewer voit les emotes d'un utilisateur : il ne doit
+// masquer ni les emotes, ni cet utilisateur
+func (s *Service) ShowsEmote(viewer, from string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.emotesMuted[viewer] && !s.ignored[viewer][from]
+}
+
+// EmotesMuted indique si viewer masque toutes les emotes
+func (s *Service) EmotesMuted(viewer string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.emotesMuted[viewer]
+}
@@ -0,0 +1,76 @@
+package game
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// ArchivedGame représente une partie terminée retirée du gestionnaire
+type ArchivedGame struct {
+	ID         string    `json:"id"`
+	ArchivedAt time.Time `json:"archived_at"`
+	EndReason  EndReason `json:"end_reason"`
+	Result     *Result   `json:"result,omitempty"`
+	View       *GameView `json:"view"`
+	Snapshot   *Snapshot `json:"snapshot"`
+}
+
+// Archive conserve les parties terminées
+type Archive interface {
+	Save(game *ArchivedGame) error
+	Get(id string) (*ArchivedGame, bool)
+	List() []*ArchivedGame
+}
+
+// MemoryArchive est une archive en mémoire
+type MemoryArchive struct {
+	games map[string]*ArchivedGame
+	mu    sync.RWMutex
+}
+
+// NewMemoryArchive crée une archive en mémoire
+func NewMemoryArchive() *MemoryArchive {
+	return &MemoryArchive{
+		games: make(map[string]*ArchivedGame),
+	}
+}
+
+func (a *MemoryArchive) Save(game *ArchivedGame) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.games[game.ID] = game
+	return nil
+}
+
+func (a *MemoryArchive) Get(id string) (*ArchivedGame, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	game, exists := a.games[id]
+	return game, exists
+}
+
+func (a *MemoryArchive) List() []*ArchivedGame {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	games := make([]*ArchivedGame, 0, len(a.games))
+	for _, game := range a.games {
+		games = append(games, game)
+	}
+	sort.Slice(games, func(i, j int) bool { return games[i].ArchivedAt.After(games[j].ArchivedAt) })
+	return games
+}
+
+// archiveGame construit l'entrée d'archive d'une partie terminée
+func archiveGame(g *Game) *ArchivedGame {
+	snapshot := g.Snapshot()
+	return &ArchivedGame{
+		ID:         snapshot.ID,
+		ArchivedAt: time.Now(),
+		EndReason:  snapshot.EndReason,
+		Result:     snapshot.Result,
+		View:       g.View(""),
+		Snapshot:   snapshot,
+	}
+}
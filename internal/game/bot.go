@@ -0,0 +1,378 @@
+package game
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+)
+
+var (
+	ErrUnknownBot = errors.New("unknown bot kind")
+	ErrNotABot    = errors.New("player is not a bot")
+	ErrNotWaiting = errors.New("bots can only be changed before the game starts")
+	ErrGameFull   = errors.New("game is full")
+)
+
+// BotHeuristic est le bot par défaut, qui suit des règles simples
+const BotHeuristic = "heuristic"
+
+// botNamePrefix préfixe le nom des bots ; le point est interdit dans les noms
+// de compte, un bot ne peut donc pas prendre le nom d'un utilisateur
+const botNamePrefix = "bot."
+
+// Bot choisit l'action d'un siège joué par le serveur à partir de la seule
+// vue de ce siège. Une commande illégale est remplacée par l'action par défaut.
+type Bot interface {
+	Decide(view *GameView) Command
+}
+
+// Planner est implémenté par les bots dont la décision est longue à calculer :
+// Plan lit l'état du bot sous le verrou de la partie et retourne la recherche,
+// qui ne touche plus ni au bot ni à la partie et tourne hors du verrou
+type Planner interface {
+	Plan(view *GameView) func() Command
+}
+
+// Observer est implémenté par les bots qui suivent les événements publics de
+// la partie, ceux que reçoivent aussi les joueurs
+type Observer interface {
+	Observe(event Event)
+}
+
+// botJoined accompagne l'événement player_joined d'un bot
+type botJoined struct {
+	Bot string `json:"bot"`
+}
+
+// botKinds associe chaque sorte de bot à son constructeur
+var botKinds = map[string]func() Bot{
+	BotHeuristic: func() Bot { return HeuristicBot{} },
+	BotEasy:      func() Bot { return NewStrategicBot(BotEasy) },
+	BotNormal:    func() Bot { return NewStrategicBot(BotNormal) },
+	BotHard:      func() Bot { return NewStrategicBot(BotHard) },
+}
+
+// RegisterBot ajoute une sorte de bot que l'hôte peut installer, à appeler au démarrage
+func RegisterBot(kind string, factory func() Bot) {
+	botKinds[kind] = factory
+}
+
+// BotKinds retourne les sortes de bots disponibles
+func BotKinds() []string {
+	kinds := make([]string, 0, len(botKinds))
+	for kind := range botKinds {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// AddBot installe un bot sur un siège libre d'une partie en attente
+func (g *Game) AddBot(kind string) (*Player, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, exists := botKinds[kind]; !exists {
+		return nil, ErrUnknownBot
+	}
+	if g.State != GameStateWaiting {
+		return nil, ErrNotWaiting
+	}
+
+	name := ""
+	for i := 1; name == "" || g.Players[name] != nil; i++ {
+		name = botNamePrefix + strconv.Itoa(i)
+	}
+	player := NewPlayer(name, 0)
+	player.Bot = true
+	player.BotKind = kind
+	if err := g.change(func() error { return g.addPlayer(player) }); err != nil {
+		return nil, err
+	}
+	return player, nil
+}
+
+// RemoveBot retire un bot d'une partie en attente
+func (g *Game) RemoveBot(name string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.State != GameStateWaiting {
+		return ErrNotWaiting
+	}
+	player, exists := g.Players[name]
+	if !exists || player.BotKind == "" {
+		return ErrNotABot
+	}
+	if err := g.change(func() error { return g.removePlayer(name) }); err != nil {
+		return err
+	}
+	delete(g.bots, name)
+	return nil
+}
+
+// botCommand retourne l'action choisie par le bot d'un siège, le verrou doit être tenu
+func (g *Game) botCommand(player *Player) Command {
+	cmd := g.botPlan(player)()
+	cmd.Player = player.Name
+	return cmd
+}
+
+// botPlan prépare la décision du bot d'un siège sur une copie de sa vue, le
+// verrou doit être tenu ; seule la recherche d'un Planner reste à calculer
+func (g *Game) botPlan(player *Player) func() Command {
+	bot := g.botFor(player)
+	view := g.view(player.Name)
+	if planner, ok := bot.(Planner); ok {
+		return planner.Plan(view)
+	}
+	cmd := bot.Decide(view)
+	return func() Command { return cmd }
+}
+
+// botFor retourne le bot d'un siège, créé au premier coup avec les événements
+// publics déjà connus ; un siège passé au bot après trop de délais dépassés
+// est joué par le bot par défaut
+func (g *Game) botFor(player *Player) Bot {
+	if bot, exists := g.bots[player.Name]; exists {
+		return bot
+	}
+	factory, exists := botKinds[player.BotKind]
+	if !exists {
+		factory = botKinds[BotHeuristic]
+	}
+	bot := factory()
+	if observer, ok := bot.(Observer); ok {
+		for _, event := range g.events {
+			observer.Observe(event.public())
+		}
+	}
+	if g.bots == nil {
+		g.bots = make(map[string]Bot)
+	}
+	g.bots[player.Name] = bot
+	return bot
+}
+
+// notifyBots transmet un événement public aux bots qui suivent la partie, le verrou doit être tenu
+func (g *Game) notifyBots(event Event) {
+	for _, bot := range g.bots {
+		if observer, ok := bot.(Observer); ok {
+			observer.Observe(event.public())
+		}
+	}
+}
+
+// HeuristicBot pare dès qu'il le peut, pose ses propriétés, pioche, puis
+// attaque l'adversaire à portée le plus affaibli avec sa meilleure arme
+type HeuristicBot struct{}
+
+// Decide choisit une action à partir de la vue du bot
+func (HeuristicBot) Decide(view *GameView) Command {
+	me := view.Players[view.Viewer]
+	if len(view.Reactions) > 0 {
+		return Command{Type: CommandRespond, Card: answerFor(view.Reactions[0], view.Hand)}
+	}
+	if view.Turn == nil || view.Turn.Phase == PhaseDiscard {
+		return Command{Type: CommandDiscard, Cards: leastUseful(view.Hand, len(view.Hand)-HandLimit)}
+	}
+
+	seats := viewSeating(view)
+	var best Command
+	bestScore := 0
+	for _, card := range view.Hand {
+		cmd, score := scorePlay(view, seats, me, card)
+		if score > bestScore {
+			best, bestScore = cmd, score
+		}
+	}
+	if bestScore > 0 {
+		best.Type = CommandPlay
+		return best
+	}
+	// Nobunaga échange ses points de vie de trop contre des cartes
+	if isCharacter(me, CharacterNobunaga) && me.Life > 2 {
+		return Command{Type: CommandAbility}
+	}
+	return Command{Type: CommandEndTurn}
+}
+
+// answerFor retourne la carte qui répond à une réaction, ou 0 pour la subir
+func answerFor(reaction *Reaction, hand []Card) int {
+	answer := 0
+	for _, card := range hand {
+		switch reaction.Kind {
+		case ReactionParry, ReactionBattlecry:
+			if card.Name == CardParry {
+				return card.ID
+			}
+		case ReactionJujitsu:
+			// Sacrifier l'arme la moins utile
+			if card.IsWeapon() && (answer == 0 || cardValue(card) < cardValue(*findByID(hand, answer))) {
+				answer = card.ID
+			}
+		}
+	}
+	return answer
+}
+
+// scorePlay retourne la meilleure façon de jouer une carte et son intérêt, 0
+// si elle ne doit pas être jouée maintenant
+func scorePlay(view *GameView, seats []*PlayerView, me *PlayerView, card Card) (Command, int) {
+	cmd := Command{Card: card.ID}
+	switch {
+	case card.Kind == CardKindProperty:
+		return cmd, 90
+	case card.Name == CardDaimyo:
+		return cmd, 80
+	case card.Name == CardTeaCeremony:
+		return cmd, 70
+	case card.Name == CardMeditation:
+		if me.Character != nil && me.Life < me.Character.Life {
+			return cmd, 60
+		}
+	case card.IsWeapon():
+		if view.Turn.WeaponsPlayed >= viewWeaponLimit(me) {
+			return cmd, 0
+		}
+		best := 0
+		for _, target := range seats {
+			hostility := hostility(me, target)
+			if target == me || target.Life <= 0 || hostility == 0 {
+				continue
+			}
+			if !isCharacter(me, CharacterKojiro) && card.Range < viewDistance(seats, me, target) {
+				continue
+			}
+			// Préférer les ennemis connus, puis les plus affaiblis, avec l'arme la plus forte
+			score := 20 + hostility*10 + card.Damage*3 - target.Life
+			if score > best {
+				best = score
+				cmd.Target = target.Name
+			}
+		}
+		return cmd, best
+	case card.Name == CardBattlecry || card.Name == CardJujitsu:
+		// Un Samouraï n'attaque pas son Shogun
+		if me.Role != RoleSamurai {
+			return cmd, 30
+		}
+	case card.Name == CardGeisha:
+		if target := mostExposed(seats, me, true); target != nil {
+			cmd.Target = target.Name
+			if len(target.InPlay) > 0 {
+				cmd.TargetCard = target.InPlay[0].ID
+			}
+			return cmd, 25
+		}
+	case card.Name == CardDiversion:
+		if target := mostExposed(seats, me, false); target != nil {
+			cmd.Target = target.Name
+			return cmd, 25
+		}
+	}
+	return cmd, 0
+}
+
+// hostility mesure l'intérêt d'affaiblir un autre joueur selon ce que le bot
+// sait des rôles : 0 pour un allié, 2 pour un ennemi connu, 1 sinon
+func hostility(me, other *PlayerView) int {
+	switch {
+	case me.Role == RoleSamurai && other.Role == RoleShogun:
+		return 0
+	case (me.Role == RoleNinja || me.Role == RoleRonin) && other.Role == RoleShogun:
+		return 2
+	}
+	return 1
+}
+
+// mostExposed retourne l'adversaire qui a le plus de propriétés posées, ou de
+// cartes en main si properties est faux
+func mostExposed(seats []*PlayerView, me *PlayerView, properties bool) *PlayerView {
+	var best *PlayerView
+	bestCount := 0
+	for _, other := range seats {
+		if other == me || hostility(me, other) == 0 {
+			continue
+		}
+		count := other.HandSize
+		if properties {
+			count += len(other.InPlay) * 10
+		}
+		if count > bestCount {
+			best, bestCount = other, count
+		}
+	}
+	return best
+}
+
+// leastUseful retourne les count cartes de la main les moins utiles
+func leastUseful(hand []Card, count int) []int {
+	if count <= 0 {
+		return nil
+	}
+	sorted := append([]Card(nil), hand...)
+	sort.SliceStable(sorted, func(i, j int) bool { return cardValue(sorted[i]) < cardValue(sorted[j]) })
+	ids := make([]int, 0, count)
+	for _, card := range sorted[:count] {
+		ids = append(ids, card.ID)
+	}
+	return ids
+}
+
+// cardValue estime l'intérêt de garder une carte en main
+func cardValue(card Card) int {
+	switch {
+	case card.IsWeapon():
+		return card.Damage*2 + card.Range
+	case card.Name == CardParry:
+		return 10
+	case card.Kind == CardKindProperty, card.Name == CardDaimyo, card.Name == CardTeaCeremony:
+		return 8
+	case card.Name == CardBattlecry, card.Name == CardJujitsu:
+		return 6
+	}
+	return 4
+}
+
+// findByID retourne une carte d'une pile par son identifiant
+func findByID(cards []Card, id int) *Card {
+	if index := findCard(cards, id); index >= 0 {
+		return &cards[index]
+	}
+	return nil
+}
+
+// viewSeating retourne les joueurs d'une vue dans l'ordre des places
+func viewSeating(view *GameView) []*PlayerView {
+	seats := make([]*PlayerView, 0, len(view.Players))
+	for _, player := range view.Players {
+		seats = append(seats, player)
+	}
+	sort.Slice(seats, func(i, j int) bool { return seats[i].Position < seats[j].Position })
+	return seats
+}
+
+// viewDistance calcule depuis une vue la difficulté d'une attaque, comme distance
+func viewDistance(seats []*PlayerView, from, to *PlayerView) int {
+	return seatDistance(viewSeatIndex(seats, from), viewSeatIndex(seats, to), len(seats)) + guard(to.InPlay, to.Character)
+}
+
+func viewSeatIndex(seats []*PlayerView, player *PlayerView) int {
+	for i, seat := range seats {
+		if seat == player {
+			return i
+		}
+	}
+	return -1
+}
+
+// viewWeaponLimit calcule depuis une vue le nombre d'armes jouables par tour, comme weaponLimit
+func viewWeaponLimit(p *PlayerView) int {
+	return weaponsPerTurn(p.InPlay, p.Character)
+}
+
+// isCharacter indique si un joueur vu incarne le personnage donné
+func isCharacter(p *PlayerView, characterID int) bool {
+	return embodies(p.Character, characterID)
+}
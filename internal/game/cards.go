@@ -0,0 +1,120 @@
+package game
+
+// CardKind représente la famille d'une carte
+type CardKind string
+
+const (
+	CardKindWeapon   CardKind = "WEAPON"
+	CardKindAction   CardKind = "ACTION"
+	CardKindProperty CardKind = "PROPERTY"
+)
+
+// Noms des cartes action et propriété utilisés par le moteur de règles
+const (
+	CardParry       = "Parade"
+	CardBattlecry   = "Cri de guerre"
+	CardJujitsu     = "Jiu-jitsu"
+	CardTeaCeremony = "Cérémonie du thé"
+	CardDiversion   = "Diversion"
+	CardGeisha      = "Geisha"
+	CardMeditation  = "Méditation"
+	CardDaimyo      = "Daimyo"
+	CardArmor       = "Armure"
+	CardFocus       = "Concentration"
+	CardFastDraw    = "Attaque rapide"
+)
+
+// Card représente une carte du paquet
+type Card struct {
+	ID     int      `json:"id"`
+	Name   string   `json:"name"`
+	Kind   CardKind `json:"kind"`
+	Range  int      `json:"range,omitempty"`
+	Damage int      `json:"damage,omitempty"`
+}
+
+// cardSpec décrit un type de carte et son nombre d'exemplaires
+type cardSpec struct {
+	name   string
+	kind   CardKind
+	rng    int
+	damage int
+	count  int
+}
+
+// deckSpecs liste la composition du paquet de base
+var deckSpecs = []cardSpec{
+	{"Bokken", CardKindWeapon, 1, 1, 6},
+	{"Bo", CardKindWeapon, 2, 1, 5},
+	{"Kiseru", CardKindWeapon, 1, 2, 5},
+	{"Kusarigama", CardKindWeapon, 2, 2, 4},
+	{"Shuriken", CardKindWeapon, 3, 1, 3},
+	{"Kanabo", CardKindWeapon, 3, 2, 1},
+	{"Katana", CardKindWeapon, 2, 3, 1},
+	{"Wakizashi", CardKindWeapon, 1, 3, 1},
+	{"Nodachi", CardKindWeapon, 3, 3, 1},
+	{"Nagayari", CardKindWeapon, 4, 2, 1},
+	{"Naginata", CardKindWeapon, 4, 1, 2},
+	{"Daikyu", CardKindWeapon, 5, 2, 1},
+	{"Tanegashima", CardKindWeapon, 5, 1, 1},
+	{CardParry, CardKindAction, 0, 0, 15},
+	{CardBattlecry, CardKindAction, 0, 0, 4},
+	{CardJujitsu, CardKindAction, 0, 0, 3},
+	{CardTeaCeremony, CardKindAction, 0, 0, 4},
+	{CardDiversion, CardKindAction, 0, 0, 5},
+	{CardGeisha, CardKindAction, 0, 0, 6},
+	{CardMeditation, CardKindAction, 0, 0, 3},
+	{CardDaimyo, CardKindAction, 0, 0, 3},
+	{CardArmor, CardKindProperty, 0, 0, 4},
+	{CardFocus, CardKindProperty, 0, 0, 6},
+	{CardFastDraw, CardKindProperty, 0, 0, 3},
+}
+
+// newDeck construit le paquet complet avec des identifiants uniques
+func newDeck() []Card {
+	deck := make([]Card, 0, 90)
+	id := 1
+	for _, spec := range deckSpecs {
+		for i := 0; i < spec.count; i++ {
+			deck = append(deck, Card{
+				ID:     id,
+				Name:   spec.name,
+				Kind:   spec.kind,
+				Range:  spec.rng,
+				Damage: spec.damage,
+			})
+			id++
+		}
+	}
+	return deck
+}
+
+// DeckSize retourne le nombre total de cartes du paquet de base
+func DeckSize() int {
+	total := 0
+	for _, spec := range deckSpecs {
+		total += spec.count
+	}
+	return total
+}
+
+// IsWeapon indique si la carte est une arme
+func (c Card) IsWeapon() bool {
+	return c.Kind == CardKindWeapon
+}
+
+// findCard retourne l'index d'une carte dans une pile, ou -1
+func findCard(cards []Card, id int) int {
+	for i, card := range cards {
+		if card.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// removeCard retire une carte d'une pile par son index
+func removeCard(cards []Card, index int) ([]Card, Card) {
+	card := cards[index]
+	return append(cards[:index:index], cards[index+1:]...), card
+}
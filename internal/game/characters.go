@@ -0,0 +1,45 @@
+package game
+
+// Character représente un personnage de assets/perso.json
+type Character struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Life int    `json:"life"`
+}
+
+// Identifiants des personnages dont la capacité est appliquée par le moteur
+const (
+	CharacterEnkei     = 1
+	CharacterChiyome   = 2
+	CharacterGinchiyo  = 3
+	CharacterGoemon    = 4
+	CharacterHanzo     = 5
+	CharacterHideyoshi = 6
+	CharacterIeyasu    = 7
+	CharacterKojiro    = 8
+	CharacterMusashi   = 9
+	CharacterNobunaga  = 10
+	CharacterTomoe     = 11
+	CharacterUshiwaka  = 12
+)
+
+// Characters liste les personnages jouables, dans l'ordre de assets/perso.json
+var Characters = []Character{
+	{CharacterEnkei, "Enkei", 5},
+	{CharacterChiyome, "Chiyome", 4},
+	{CharacterGinchiyo, "Ginchiyo", 4},
+	{CharacterGoemon, "Goemon", 5},
+	{CharacterHanzo, "Hanzõ", 4},
+	{CharacterHideyoshi, "Hideyoshi", 4},
+	{CharacterIeyasu, "Ieyasu", 5},
+	{CharacterKojiro, "Kojirõ", 5},
+	{CharacterMusashi, "Musashi", 5},
+	{CharacterNobunaga, "Nobunaga", 5},
+	{CharacterTomoe, "Tomoe", 5},
+	{CharacterUshiwaka, "Ushiwaka", 4},
+}
+
+// is indique si le joueur incarne le personnage donné
+func (p *Player) is(characterID int) bool {
+	return embodies(p.Character, characterID)
+}
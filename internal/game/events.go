@@ -0,0 +1,105 @@
+package game
+
+import "time"
+
+// Types d'événements enregistrés dans l'historique d'une partie
+const (
+	EventPlayerJoined       = "player_joined"
+	EventPlayerLeft         = "player_left"
+	EventGameStarted        = "game_started"
+	EventGameCreated        = "game_created"
+	EventCommand            = "command"
+	EventAutoCommand        = "auto_command"
+	EventPauseVote          = "pause_vote"
+	EventGamePaused         = "game_paused"
+	EventGameResumed        = "game_resumed"
+	EventGameEnded          = "game_ended"
+	EventPlayerDisconnected = "player_disconnected"
+	EventPlayerReconnected  = "player_reconnected"
+	EventSeatReleased       = "seat_released"
+	EventPlayerKicked       = "player_kicked"
+)
+
+// eventHistorySize est le nombre d'événements conservés pour rejouer une reconnexion
+const eventHistorySize = 256
+
+// Event représente un changement public de la partie, numéroté dans l'ordre
+type Event struct {
+	Seq    int64       `json:"seq"`
+	Type   string      `json:"type"`
+	Player string      `json:"player,omitempty"`
+	Data   interface{} `json:"data,omitempty"`
+	At     time.Time   `json:"at"`
+}
+
+// record ajoute un événement à l'historique, l'écrit dans le journal puis
+// sauvegarde la partie, le verrou de la partie doit être tenu. Si le journal
+// n'a pas pu être écrit, la partie n'est pas sauvegardée et l'erreur est
+// retournée pour que l'appelant annule le changement.
+func (g *Game) record(eventType string, player string, data interface{}) error {
+	g.UpdatedAt = g.now()
+	g.pendingAt = time.Time{}
+	g.Seq++
+	g.events = append(g.events, Event{
+		Seq:    g.Seq,
+		Type:   eventType,
+		Player: player,
+		Data:   data,
+		At:     g.UpdatedAt,
+	})
+	if len(g.events) > eventHistorySize {
+		g.events = g.events[len(g.events)-eventHistorySize:]
+	}
+	g.recordSpectatorView()
+	g.notifyBots(g.events[len(g.events)-1])
+	if g.replaying {
+		return nil
+	}
+	if err := g.appendToLog(g.events[len(g.events)-1]); err != nil {
+		return err
+	}
+	g.save()
+	return nil
+}
+
+// LastSeq retourne le numéro du dernier événement de la partie
+func (g *Game) LastSeq() int64 {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.Seq
+}
+
+// EventsSince retourne les événements postérieurs à seq, ou false s'ils ne sont plus tous en mémoire
+func (g *Game) EventsSince(seq int64) ([]Event, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if seq >= g.Seq {
+		return []Event{}, true
+	}
+	if len(g.events) == 0 || g.events[0].Seq > seq+1 {
+		return nil, false
+	}
+
+	start := len(g.events) - int(g.Seq-seq)
+	events := make([]Event, 0, len(g.events)-start)
+	for _, event := range g.events[start:] {
+		events = append(events, event.public())
+	}
+	return events, true
+}
+
+// public retire d'un événement ce qui ne doit pas sortir du serveur, comme la graine de la partie
+func (e Event) public() Event {
+	if e.Type == EventGameCreated {
+		e.Data = nil
+	}
+	return e
+}
+
+// IdleFor retourne le temps écoulé depuis le dernier événement de la partie
+func (g *Game) IdleFor() time.Duration {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return time.Since(g.UpdatedAt)
+}
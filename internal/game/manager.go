@@ -1,14 +1,21 @@
 package game
 
 import (
+	"log"
 	"sync"
 )
 
 // GameManager gère toutes les parties en cours
 type GameManager struct {
-	games   map[string]*Game
-	mu      sync.RWMutex
-	current *Game
+	games      map[string]*Game
+	mu         sync.RWMutex
+	current    *Game
+	archive    Archive
+	repository GameRepository
+	commandLog CommandLog
+	rules      Ruleset
+	onTimeout  func(g *Game, event string)
+	onRemove   func(g *Game)
 }
 
 var (
@@ -20,21 +27,40 @@ var (
 func GetGameManager() *GameManager {
 	once.Do(func() {
 		instance = &GameManager{
-			games: make(map[string]*Game),
+			games:      make(map[string]*Game),
+			archive:    NewMemoryArchive(),
+			repository: NewMemoryRepository(),
+			rules:      DefaultRuleset(),
 		}
 	})
 	return instance
 }
 
 // CreateGame crée une nouvelle partie
-func (gm *GameManager) CreateGame(createdBy string) *Game {
+func (gm *GameManager) CreateGame(createdBy string) (*Game, error) {
 	gm.mu.Lock()
 	defer gm.mu.Unlock()
 
-	game := NewGame(createdBy)
+	game, err := gm.newGame(createdBy)
+	if err != nil {
+		return nil, err
+	}
 	gm.games[game.ID] = game
 	gm.current = game
-	return game
+	return game, nil
+}
+
+// newGame crée une partie avec les règles par défaut du gestionnaire ; une
+// partie dont la création n'a pas pu être journalisée est abandonnée
+func (gm *GameManager) newGame(createdBy string) (*Game, error) {
+	game := NewGame(createdBy)
+	game.Rules = gm.rules
+	game.repository = gm.repository
+	game.commandLog = gm.commandLog
+	if err := game.recordCreation(); err != nil {
+		return nil, err
+	}
+	return game, nil
 }
 
 // GetCurrentGame retourne la partie actuelle
@@ -45,31 +71,170 @@ func (gm *GameManager) GetCurrentGame() *Game {
 }
 
 // GetOrCreateCurrentGame retourne la partie actuelle ou en crée une
-func (gm *GameManager) GetOrCreateCurrentGame(createdBy string) *Game {
+func (gm *GameManager) GetOrCreateCurrentGame(createdBy string) (*Game, error) {
 	gm.mu.Lock()
 	defer gm.mu.Unlock()
 
 	if gm.current == nil {
-		gm.current = NewGame(createdBy)
-		gm.games[gm.current.ID] = gm.current
+		game, err := gm.newGame(createdBy)
+		if err != nil {
+			return nil, err
+		}
+		gm.current = game
+		gm.games[game.ID] = game
 	}
-	return gm.current
+	return gm.current, nil
 }
 
 // JoinCurrentGame fait rejoindre un joueur à la partie actuelle
-func (gm *GameManager) JoinCurrentGame(playerName string) (*Player, bool) {
+func (gm *GameManager) JoinCurrentGame(playerName string) (*Player, error) {
 	gm.mu.Lock()
 	defer gm.mu.Unlock()
 
 	if gm.current == nil {
-		gm.current = NewGame(playerName)
-		gm.games[gm.current.ID] = gm.current
+		game, err := gm.newGame(playerName)
+		if err != nil {
+			return nil, err
+		}
+		gm.current = game
+		gm.games[game.ID] = game
 	}
 
 	player := NewPlayer(playerName, 0)
-	success := gm.current.AddPlayer(player)
-	if success {
-		return player, true
+	if err := gm.current.AddPlayer(player); err != nil {
+		return nil, err
+	}
+	return player, nil
+}
+
+// GetGame retourne une partie par son identifiant
+func (gm *GameManager) GetGame(id string) *Game {
+	gm.mu.RLock()
+	defer gm.mu.RUnlock()
+	return gm.games[id]
+}
+
+// GetGames retourne toutes les parties connues
+func (gm *GameManager) GetGames() []*Game {
+	gm.mu.RLock()
+	defer gm.mu.RUnlock()
+
+	games := make([]*Game, 0, len(gm.games))
+	for _, game := range gm.games {
+		games = append(games, game)
+	}
+	return games
+}
+
+// OnTimeout enregistre la fonction appelée après chaque action jouée automatiquement
+func (gm *GameManager) OnTimeout(handler func(g *Game, event string)) {
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+	gm.onTimeout = handler
+}
+
+func (gm *GameManager) notifyTimeout(g *Game, event string) {
+	gm.mu.RLock()
+	handler := gm.onTimeout
+	gm.mu.RUnlock()
+
+	if handler != nil {
+		handler(g, event)
+	}
+}
+
+// OnRemove enregistre la fonction appelée quand une partie est retirée du gestionnaire
+func (gm *GameManager) OnRemove(handler func(g *Game)) {
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+	gm.onRemove = handler
+}
+
+// SetArchive remplace l'archive des parties terminées
+func (gm *GameManager) SetArchive(archive Archive) {
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+	gm.archive = archive
+}
+
+// GetArchive retourne l'archive des parties terminées
+func (gm *GameManager) GetArchive() Archive {
+	gm.mu.RLock()
+	defer gm.mu.RUnlock()
+	return gm.archive
+}
+
+// SetDefaultRules définit les règles appliquées aux nouvelles parties
+func (gm *GameManager) SetDefaultRules(rules Ruleset) {
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+	gm.rules = rules
+}
+
+// SetRepository remplace le stockage des parties et restaure les parties qu'il contient
+func (gm *GameManager) SetRepository(repository GameRepository) error {
+	snapshots, err := repository.LoadAll()
+	if err != nil {
+		return err
+	}
+
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+
+	gm.repository = repository
+	for _, snapshot := range snapshots {
+		game := RestoreGame(snapshot, repository)
+		gm.games[game.ID] = game
+
+		// La partie la plus récemment active redevient la partie actuelle
+		if gm.current == nil || game.UpdatedAt.After(gm.current.UpdatedAt) {
+			gm.current = game
+		}
+	}
+	return nil
+}
+
+// SetCommandLog branche le journal des parties et rejoue les événements qu'il contient,
+// en complétant les parties déjà restaurées depuis leur dernier snapshot
+func (gm *GameManager) SetCommandLog(commandLog CommandLog) error {
+	ids, err := commandLog.GameIDs()
+	if err != nil {
+		return err
+	}
+
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+
+	gm.commandLog = commandLog
+	for _, game := range gm.games {
+		game.setCommandLog(commandLog)
+	}
+
+	for _, id := range ids {
+		entries, err := commandLog.Load(id)
+		if err != nil {
+			return err
+		}
+
+		game, exists := gm.games[id]
+		if exists {
+			err = game.Replay(entries)
+		} else {
+			game, err = ReplayGame(entries)
+		}
+		if err != nil {
+			log.Printf("Partie %s restaurée partiellement: %v", id, err)
+			if game == nil {
+				continue
+			}
+		}
+
+		game.setCommandLog(commandLog)
+		game.setRepository(gm.repository)
+		gm.games[id] = game
+		if gm.current == nil || game.UpdatedAt.After(gm.current.UpdatedAt) {
+			gm.current = game
+		}
 	}
-	return nil, false
+	return nil
 }
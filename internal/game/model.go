@@ -1,6 +1,8 @@
 package game
 
 import (
+    "errors"
+    "math/rand"
     "sync"
     "time"
 )
@@ -8,6 +10,15 @@ import (
 // GameState représente l'état actuel du jeu
 type GameState string
 
+// MinPlayers est le nombre minimum de joueurs pour lancer une partie
+const MinPlayers = 3
+
+var (
+    ErrGameInProgress   = errors.New("game has already started")
+    ErrAlreadyJoined    = errors.New("player already joined the game")
+    ErrNotEnoughPlayers = errors.New("not enough players to start the game")
+)
+
 const (
     GameStateWaiting GameState = "WAITING"
     GameStateStarted GameState = "STARTED"
@@ -22,6 +33,17 @@ type Player struct {
     Life     int    `json:"life"`
     Honor    int    `json:"honor"`
     JoinedAt time.Time `json:"joined_at"`
+    Character *Character `json:"character,omitempty"`
+    InPlay    []Card     `json:"in_play,omitempty"`
+    DisconnectedAt *time.Time `json:"disconnected_at,omitempty"`
+    Abandoned bool       `json:"abandoned,omitempty"`
+    Kicked    bool       `json:"kicked,omitempty"`
+    Timeouts  int        `json:"timeouts"`
+    AFK       bool       `json:"afk,omitempty"`
+    Bot       bool       `json:"bot,omitempty"`
+    BotKind   string     `json:"bot_kind,omitempty"`
+    Role      Role       `json:"-"`
+    Hand      []Card     `json:"-"`
 }
 
 // Game représente l'état complet d'une partie
@@ -31,19 +53,53 @@ type Game struct {
     Players     map[string]*Player `json:"players"`
     CreatedBy   string            `json:"created_by"`
     CreatedAt   time.Time         `json:"created_at"`
+    UpdatedAt   time.Time         `json:"updated_at"`
     MaxPlayers  int               `json:"max_players"`
+    Turn        *Turn             `json:"turn,omitempty"`
+    Reactions   []*Reaction       `json:"reactions,omitempty"`
+    Exhaustions int               `json:"exhaustions"`
+    Result      *Result           `json:"result,omitempty"`
+    EndReason   EndReason         `json:"end_reason,omitempty"`
+    PausedAt    *time.Time        `json:"paused_at,omitempty"`
+    PauseReason PauseReason       `json:"pause_reason,omitempty"`
+    PauseVotes  map[string]bool   `json:"pause_votes,omitempty"`
+    Seq         int64             `json:"seq"`
+    Rules       Ruleset           `json:"rules"`
+    Deadline    *time.Time        `json:"deadline,omitempty"`
+    Spectators  int               `json:"spectators"`
+    Seed        int64             `json:"-"`
+    Commands    int               `json:"-"`
+    Deck        []Card            `json:"-"`
+    Discard     []Card            `json:"-"`
+    events      []Event
+    spectatorViews []*GameView
+    repository  GameRepository
+    commandLog  CommandLog
+    replaying   bool
+    replayTimes map[int64]time.Time
+    pendingAt   time.Time
+    timer       *time.Timer
+    timerGeneration int
+    remaining   time.Duration
+    rng         *rand.Rand
+    bots        map[string]Bot
+    manualBots  bool // les bots sont joués par l'appelant, sans délai armé
     mu          sync.RWMutex
 }
 
 // NewGame crée une nouvelle partie
 func NewGame(createdBy string) *Game {
+    now := time.Now()
     return &Game{
         ID:         generateGameID(),
         State:      GameStateWaiting,
         Players:    make(map[string]*Player),
         CreatedBy:  createdBy,
-        CreatedAt:  time.Now(),
+        CreatedAt:  now,
+        UpdatedAt:  now,
         MaxPlayers: 7, // Maximum 7 joueurs selon votre interface
+        Seed:       now.UnixNano(),
+        Rules:      DefaultRuleset(),
     }
 }
 
@@ -59,36 +115,59 @@ func NewPlayer(name string, position int) *Player {
 }
 
 // AddPlayer ajoute un joueur à la partie
-func (g *Game) AddPlayer(player *Player) bool {
+func (g *Game) AddPlayer(player *Player) error {
     g.mu.Lock()
     defer g.mu.Unlock()
+    return g.change(func() error { return g.addPlayer(player) })
+}
+
+// addPlayer ajoute un joueur, le verrou doit être tenu
+func (g *Game) addPlayer(player *Player) error {
+    // Une partie lancée, en pause ou terminée ne prend plus de nouveau siège
+    if g.State != GameStateWaiting {
+        return ErrGameInProgress
+    }
 
     // Vérifier si la partie n'est pas pleine
     if len(g.Players) >= g.MaxPlayers {
-        return false
+        return ErrGameFull
     }
 
     // Vérifier si le joueur n'est pas déjà dans la partie
     if _, exists := g.Players[player.Name]; exists {
-        return false
+        return ErrAlreadyJoined
     }
 
     // Attribuer la prochaine position disponible
     player.Position = g.getNextPosition()
+    player.JoinedAt = g.now()
     g.Players[player.Name] = player
-    return true
+    if player.BotKind != "" {
+        return g.record(EventPlayerJoined, player.Name, botJoined{Bot: player.BotKind})
+    }
+    return g.record(EventPlayerJoined, player.Name, nil)
 }
 
 // RemovePlayer retire un joueur de la partie
-func (g *Game) RemovePlayer(playerName string) bool {
+func (g *Game) RemovePlayer(playerName string) error {
     g.mu.Lock()
     defer g.mu.Unlock()
 
-    if _, exists := g.Players[playerName]; exists {
-        delete(g.Players, playerName)
-        return true
+    // Une partie lancée garde ses sièges jusqu'à la fin
+    if g.State != GameStateWaiting {
+        return ErrGameInProgress
     }
-    return false
+
+    if _, exists := g.Players[playerName]; !exists {
+        return ErrNotInGame
+    }
+    return g.change(func() error { return g.removePlayer(playerName) })
+}
+
+// removePlayer retire un joueur, le verrou doit être tenu
+func (g *Game) removePlayer(playerName string) error {
+    delete(g.Players, playerName)
+    return g.record(EventPlayerLeft, playerName, nil)
 }
 
 // GetPlayers retourne la liste des joueurs
@@ -104,16 +183,36 @@ func (g *Game) GetPlayers() map[string]*Player {
     return players
 }
 
+// GetState retourne l'état de la partie
+func (g *Game) GetState() GameState {
+    g.mu.RLock()
+    defer g.mu.RUnlock()
+    return g.State
+}
+
 // StartGame démarre la partie
-func (g *Game) StartGame() bool {
+func (g *Game) StartGame() error {
     g.mu.Lock()
     defer g.mu.Unlock()
 
-    if g.State == GameStateWaiting && len(g.Players) >= 2 {
-        g.State = GameStateStarted
-        return true
+    if g.State != GameStateWaiting {
+        return ErrGameInProgress
+    }
+    if len(g.Players) < MinPlayers {
+        return ErrNotEnoughPlayers
+    }
+    return g.change(g.startGame)
+}
+
+// startGame distribue les cartes et lance le premier tour, le verrou doit être tenu
+func (g *Game) startGame() error {
+    g.State = GameStateStarted
+    g.deal()
+    if err := g.record(EventGameStarted, "", nil); err != nil {
+        return err
     }
-    return false
+    g.scheduleDeadline()
+    return nil
 }
 
 // getNextPosition trouve la prochaine position disponible
@@ -0,0 +1,27 @@
+package game
+
+import "testing"
+
+func TestCannotJoinAfterStart(t *testing.T) {
+	g := newTestGame(t, MinPlayers, 1)
+	join := func(state GameState) {
+		t.Helper()
+		if err := g.AddPlayer(NewPlayer("late", 0)); err != ErrGameInProgress {
+			t.Fatalf("join a %s game: got %v, want %v", state, err, ErrGameInProgress)
+		}
+		if g.HasPlayer("late") || len(g.Players) != MinPlayers {
+			t.Fatalf("a seat was added to a %s game", state)
+		}
+	}
+
+	join(GameStateStarted)
+	if err := g.Pause(g.CreatedBy); err != nil {
+		t.Fatalf("Pause: %v", err)
+	}
+	join(GameStatePaused)
+	if err := g.Resume(g.CreatedBy); err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	g.Abort(EndReasonModerator)
+	join(GameStateEnded)
+}
@@ -0,0 +1,163 @@
+package game
+
+import "errors"
+
+var (
+	ErrNotHost      = errors.New("only the host can do this")
+	ErrCannotPause  = errors.New("only a started game can be paused")
+	ErrNotPaused    = errors.New("game is not paused")
+	ErrAlreadyVoted = errors.New("player already voted")
+)
+
+// PauseReason indique pourquoi une partie a été mise en pause
+type PauseReason string
+
+const (
+	PauseReasonHost       PauseReason = "HOST"
+	PauseReasonVote       PauseReason = "VOTE"
+	PauseReasonDisconnect PauseReason = "DISCONNECT"
+)
+
+// Pause met la partie en pause à la demande de l'hôte
+func (g *Game) Pause(by string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if by != g.CreatedBy {
+		return ErrNotHost
+	}
+	if g.State != GameStateStarted {
+		return ErrCannotPause
+	}
+	return g.change(func() error { return g.pause(PauseReasonHost) })
+}
+
+// Resume reprend la partie à la demande de l'hôte
+func (g *Game) Resume(by string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if by != g.CreatedBy {
+		return ErrNotHost
+	}
+	if g.State != GameStatePaused {
+		return ErrNotPaused
+	}
+	return g.change(g.resume)
+}
+
+// VotePause enregistre un vote de pause et met la partie en pause à la majorité
+func (g *Game) VotePause(player string) (bool, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.State != GameStateStarted {
+		return false, ErrCannotPause
+	}
+	if _, exists := g.Players[player]; !exists {
+		return false, ErrNotInGame
+	}
+	if g.PauseVotes[player] {
+		return false, ErrAlreadyVoted
+	}
+	paused := false
+	err := g.change(func() error {
+		g.vote(player)
+		if !g.hasMajority() {
+			return g.record(EventPauseVote, player, nil)
+		}
+		paused = true
+		return g.pause(PauseReasonVote)
+	})
+	return paused, err
+}
+
+// VoteResume enregistre un vote de reprise et reprend la partie à la majorité
+func (g *Game) VoteResume(player string) (bool, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.State != GameStatePaused {
+		return false, ErrNotPaused
+	}
+	if _, exists := g.Players[player]; !exists {
+		return false, ErrNotInGame
+	}
+	if g.PauseVotes[player] {
+		return false, ErrAlreadyVoted
+	}
+	resumed := false
+	err := g.change(func() error {
+		g.vote(player)
+		if !g.hasMajority() {
+			return g.record(EventPauseVote, player, nil)
+		}
+		resumed = true
+		return g.resume()
+	})
+	return resumed, err
+}
+
+// PauseForDisconnect met la partie en pause si elle attend le joueur déconnecté
+func (g *Game) PauseForDisconnect(player string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.State != GameStateStarted || g.waitingOn() != player {
+		return false
+	}
+	return g.change(func() error { return g.pause(PauseReasonDisconnect) }) == nil
+}
+
+// IsPaused indique si la partie est en pause
+func (g *Game) IsPaused() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.State == GameStatePaused
+}
+
+// vote ajoute le vote d'un joueur
+func (g *Game) vote(player string) {
+	if g.PauseVotes == nil {
+		g.PauseVotes = make(map[string]bool)
+	}
+	g.PauseVotes[player] = true
+}
+
+// hasMajority indique si plus de la moitié des joueurs humains connectés ont
+// voté ; les bots et les sièges abandonnés ne votent jamais, un joueur
+// déconnecté ne compte que s'il a déjà voté
+func (g *Game) hasMajority() bool {
+	voters, votes := 0, 0
+	for name, player := range g.Players {
+		if player.autoplayed() || (player.DisconnectedAt != nil && !g.PauseVotes[name]) {
+			continue
+		}
+		voters++
+		if g.PauseVotes[name] {
+			votes++
+		}
+	}
+	return votes*2 > voters
+}
+
+// pause fige la partie : le tour, la phase et les réactions en attente restent intacts
+func (g *Game) pause(reason PauseReason) error {
+	now := g.now()
+	g.freezeDeadline()
+	g.State = GameStatePaused
+	g.PausedAt = &now
+	g.PauseReason = reason
+	g.PauseVotes = nil
+	return g.record(EventGamePaused, "", reason)
+}
+
+// resume relance la partie exactement là où elle s'était arrêtée
+func (g *Game) resume() error {
+	g.State = GameStateStarted
+	g.thawDeadline()
+	g.PausedAt = nil
+	g.PauseReason = ""
+	g.PauseVotes = nil
+	return g.record(EventGameResumed, "", nil)
+}
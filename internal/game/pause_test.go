@@ -0,0 +1,119 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHostPauseAndResume(t *testing.T) {
+	g := newTestGame(t, 4, 1)
+	turn := *g.Turn
+
+	if err := g.Pause("p2"); err != ErrNotHost {
+		t.Fatalf("pause by a player who is not the host: got %v, want %v", err, ErrNotHost)
+	}
+	if err := g.Pause(g.CreatedBy); err != nil {
+		t.Fatalf("Pause: %v", err)
+	}
+	if g.State != GameStatePaused || g.PauseReason != PauseReasonHost || g.PausedAt == nil {
+		t.Fatalf("after pause: state %s, reason %q", g.State, g.PauseReason)
+	}
+	if err := g.Apply(Command{Type: CommandEndTurn, Player: turn.Player}); err != ErrGamePaused {
+		t.Fatalf("command while paused: got %v, want %v", err, ErrGamePaused)
+	}
+	if err := g.Pause(g.CreatedBy); err != ErrCannotPause {
+		t.Fatalf("second pause: got %v, want %v", err, ErrCannotPause)
+	}
+
+	if err := g.Resume("p2"); err != ErrNotHost {
+		t.Fatalf("resume by a player who is not the host: got %v, want %v", err, ErrNotHost)
+	}
+	if err := g.Resume(g.CreatedBy); err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	if g.State != GameStateStarted || g.PausedAt != nil || g.PauseReason != "" {
+		t.Fatalf("after resume: state %s, reason %q", g.State, g.PauseReason)
+	}
+	if *g.Turn != turn {
+		t.Fatalf("resume changed the turn: %+v, want %+v", *g.Turn, turn)
+	}
+	if err := g.Resume(g.CreatedBy); err != ErrNotPaused {
+		t.Fatalf("resume a started game: got %v, want %v", err, ErrNotPaused)
+	}
+}
+
+func TestPauseVoteNeedsMajorityOfConnectedHumans(t *testing.T) {
+	g := newTestGame(t, 5, 1)
+	// Un bot ne vote pas, et un joueur déconnecté ne compte pas tant qu'il n'a pas voté
+	g.Players["p5"].Bot = true
+	away := time.Now()
+	g.Players["p4"].DisconnectedAt = &away
+
+	if paused, err := g.VotePause("p1"); err != nil || paused {
+		t.Fatalf("first of 3 votes: paused %v, err %v", paused, err)
+	}
+	if _, err := g.VotePause("p1"); err != ErrAlreadyVoted {
+		t.Fatalf("second vote of p1: got %v, want %v", err, ErrAlreadyVoted)
+	}
+	if _, err := g.VotePause("ghost"); err != ErrNotInGame {
+		t.Fatalf("vote of a stranger: got %v, want %v", err, ErrNotInGame)
+	}
+	if paused, err := g.VotePause("p2"); err != nil || !paused {
+		t.Fatalf("second of 3 votes: paused %v, err %v", paused, err)
+	}
+	if g.State != GameStatePaused || g.PauseReason != PauseReasonVote || len(g.PauseVotes) != 0 {
+		t.Fatalf("after the majority: state %s, reason %q, votes %v", g.State, g.PauseReason, g.PauseVotes)
+	}
+
+	// Le vote de reprise repart de zéro, et le joueur déconnecté qui vote compte
+	if resumed, err := g.VoteResume("p4"); err != nil || resumed {
+		t.Fatalf("first of 4 resume votes: resumed %v, err %v", resumed, err)
+	}
+	if resumed, err := g.VoteResume("p1"); err != nil || resumed {
+		t.Fatalf("second of 4 resume votes: resumed %v, err %v", resumed, err)
+	}
+	if resumed, err := g.VoteResume("p2"); err != nil || !resumed {
+		t.Fatalf("third of 4 resume votes: resumed %v, err %v", resumed, err)
+	}
+	if g.State != GameStateStarted {
+		t.Fatalf("after the resume majority: state %s", g.State)
+	}
+}
+
+func TestPauseFreezesDeadline(t *testing.T) {
+	g := newTestGame(t, MinPlayers, 1)
+	t.Cleanup(func() { g.Abort(EndReasonModerator) })
+	g.mu.Lock()
+	g.Rules.PlayTimeoutSeconds = 1
+	g.scheduleDeadline()
+	g.mu.Unlock()
+	commands := g.Commands
+
+	if err := g.Pause(g.CreatedBy); err != nil {
+		t.Fatalf("Pause: %v", err)
+	}
+	if g.Deadline != nil {
+		t.Fatalf("a paused game keeps the deadline %v", g.Deadline)
+	}
+
+	// Le délai d'une seconde ne s'écoule pas pendant la pause
+	time.Sleep(1200 * time.Millisecond)
+	g.mu.RLock()
+	played := g.Commands != commands || g.State != GameStatePaused
+	g.mu.RUnlock()
+	if played {
+		t.Fatal("the deadline expired while the game was paused")
+	}
+
+	if err := g.Resume(g.CreatedBy); err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if g.Deadline == nil {
+		t.Fatal("resume did not restore the deadline")
+	}
+	if left := time.Until(*g.Deadline); left <= 0 || left > time.Second {
+		t.Fatalf("deadline %v after resume, want the time left before the pause", left)
+	}
+}
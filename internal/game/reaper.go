@@ -0,0 +1,86 @@
+package game
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// ReaperConfig regroupe les durées de vie des parties inactives, une durée à 0 désactive le nettoyage correspondant
+type ReaperConfig struct {
+	Interval      time.Duration
+	EmptyLobbyTTL time.Duration
+	IdleGameTTL   time.Duration
+	EndedGameTTL  time.Duration
+}
+
+// RunReaper nettoie périodiquement les parties jusqu'à l'annulation du contexte
+func (gm *GameManager) RunReaper(ctx context.Context, config ReaperConfig) {
+	if config.Interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			gm.Reap(config)
+		}
+	}
+}
+
+// Reap retire les salons vides, termine les parties abandonnées et archive les parties terminées
+func (gm *GameManager) Reap(config ReaperConfig) {
+	for _, g := range gm.GetGames() {
+		idle := g.IdleFor()
+
+		switch g.GetState() {
+		case GameStateWaiting:
+			if config.EmptyLobbyTTL > 0 && len(g.GetPlayers()) == 0 && idle >= config.EmptyLobbyTTL {
+				gm.removeGame(g)
+				log.Printf("Salon vide %s supprimé", g.ID)
+			}
+
+		case GameStateStarted, GameStatePaused:
+			if config.IdleGameTTL > 0 && idle >= config.IdleGameTTL && g.Abort(EndReasonIdle) {
+				log.Printf("Partie inactive %s terminée", g.ID)
+			}
+
+		case GameStateEnded:
+			if config.EndedGameTTL > 0 && idle >= config.EndedGameTTL {
+				if err := gm.GetArchive().Save(archiveGame(g)); err != nil {
+					log.Printf("Erreur lors de l'archivage de la partie %s: %v", g.ID, err)
+					continue
+				}
+				gm.removeGame(g)
+				log.Printf("Partie %s archivée", g.ID)
+			}
+		}
+	}
+}
+
+// removeGame retire une partie du gestionnaire puis appelle la fonction enregistrée par OnRemove
+func (gm *GameManager) removeGame(g *Game) {
+	gm.mu.Lock()
+	delete(gm.games, g.ID)
+	if gm.current == g {
+		gm.current = nil
+	}
+	if err := gm.repository.Delete(g.ID); err != nil {
+		log.Printf("Erreur lors de la suppression de la partie %s: %v", g.ID, err)
+	}
+	if gm.commandLog != nil {
+		if err := gm.commandLog.Delete(g.ID); err != nil {
+			log.Printf("Erreur lors de la suppression du journal de la partie %s: %v", g.ID, err)
+		}
+	}
+	handler := gm.onRemove
+	gm.mu.Unlock()
+
+	if handler != nil {
+		handler(g)
+	}
+}
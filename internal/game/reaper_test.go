@@ -0,0 +1,79 @@
+package game
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// idleSince fait comme si la partie n'avait pas bougé depuis d pour le nettoyage
+func idleSince(g *Game, d time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.UpdatedAt = time.Now().Add(-d)
+}
+
+func TestReapEndsIdleGameThenArchivesIt(t *testing.T) {
+	log := &memoryLog{}
+	repository := NewMemoryRepository()
+	archive := NewMemoryArchive()
+	gm := &GameManager{
+		games:      make(map[string]*Game),
+		archive:    archive,
+		repository: repository,
+		commandLog: log,
+	}
+	var removed *Game
+	gm.OnRemove(func(g *Game) { removed = g })
+
+	g, err := gm.CreateGame("p1")
+	if err != nil {
+		t.Fatalf("CreateGame: %v", err)
+	}
+	for i := 1; i <= MinPlayers; i++ {
+		if err := g.AddPlayer(NewPlayer(fmt.Sprintf("p%d", i), 0)); err != nil {
+			t.Fatalf("AddPlayer: %v", err)
+		}
+	}
+	if err := g.StartGame(); err != nil {
+		t.Fatalf("StartGame: %v", err)
+	}
+	config := ReaperConfig{IdleGameTTL: time.Hour, EndedGameTTL: time.Hour}
+
+	// Une partie active n'est pas touchée
+	gm.Reap(config)
+	if g.GetState() != GameStateStarted {
+		t.Fatalf("an active game was reaped into %s", g.GetState())
+	}
+
+	// Une partie inactive au-delà de son TTL est terminée, puis gardée jusqu'au TTL des parties terminées
+	idleSince(g, 2*time.Hour)
+	gm.Reap(config)
+	if g.GetState() != GameStateEnded || g.Snapshot().EndReason != EndReasonIdle {
+		t.Fatalf("idle game is %s, want ended as idle", g.GetState())
+	}
+	gm.Reap(config)
+	if gm.GetGame(g.ID) == nil {
+		t.Fatal("a game that just ended was removed")
+	}
+
+	// Passé ce TTL, la partie est archivée puis oubliée du gestionnaire et du stockage
+	if snapshots, _ := repository.LoadAll(); len(snapshots) != 1 || len(log.events) == 0 {
+		t.Fatal("the game was not stored before the reap")
+	}
+	idleSince(g, 2*time.Hour)
+	gm.Reap(config)
+	archived, ok := archive.Get(g.ID)
+	if !ok || archived.EndReason != EndReasonIdle {
+		t.Fatalf("archive holds %+v, want the game ended as idle", archived)
+	}
+	if gm.GetGame(g.ID) != nil || gm.GetCurrentGame() != nil || removed != g {
+		t.Fatal("the archived game is still managed")
+	}
+	if snapshots, _ := repository.LoadAll(); len(snapshots) != 0 {
+		t.Fatal("the archived game is still in the repository")
+	}
+	if len(log.events) != 0 {
+		t.Fatal("the archived game's log was not deleted")
+	}
+}
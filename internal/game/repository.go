@@ -0,0 +1,69 @@
+package game
+
+import (
+	"log"
+	"sync"
+)
+
+// GameRepository stocke l'état des parties pour qu'elles survivent à un redémarrage
+type GameRepository interface {
+	Save(snapshot *Snapshot) error
+	Delete(id string) error
+	LoadAll() ([]*Snapshot, error)
+}
+
+// MemoryRepository est un stockage en mémoire, perdu au redémarrage
+type MemoryRepository struct {
+	snapshots map[string]*Snapshot
+	mu        sync.RWMutex
+}
+
+// NewMemoryRepository crée un stockage en mémoire
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		snapshots: make(map[string]*Snapshot),
+	}
+}
+
+func (r *MemoryRepository) Save(snapshot *Snapshot) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.snapshots[snapshot.ID] = snapshot
+	return nil
+}
+
+func (r *MemoryRepository) Delete(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.snapshots, id)
+	return nil
+}
+
+func (r *MemoryRepository) LoadAll() ([]*Snapshot, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	snapshots := make([]*Snapshot, 0, len(r.snapshots))
+	for _, snapshot := range r.snapshots {
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
+
+// save enregistre l'état de la partie après un changement validé, le verrou doit être tenu
+func (g *Game) save() {
+	if g.repository == nil {
+		return
+	}
+	if err := g.repository.Save(g.snapshot()); err != nil {
+		log.Printf("Erreur lors de la sauvegarde de la partie %s: %v", g.ID, err)
+	}
+}
+
+// setRepository branche le stockage de la partie et y sauvegarde son état courant
+func (g *Game) setRepository(repository GameRepository) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.repository = repository
+	g.save()
+}
@@ -0,0 +1,60 @@
+package game
+
+// Role représente le rôle caché d'un joueur
+type Role string
+
+const (
+	RoleShogun  Role = "SHOGUN"
+	RoleSamurai Role = "SAMURAI"
+	RoleNinja   Role = "NINJA"
+	RoleRonin   Role = "RONIN"
+)
+
+// Team représente une équipe en fin de partie
+type Team string
+
+const (
+	TeamShogun Team = "SHOGUN"
+	TeamNinja  Team = "NINJA"
+	TeamRonin  Team = "RONIN"
+)
+
+// rolesByPlayerCount donne la répartition des rôles selon le nombre de joueurs
+var rolesByPlayerCount = map[int][]Role{
+	3: {RoleShogun, RoleNinja, RoleRonin},
+	4: {RoleShogun, RoleSamurai, RoleNinja, RoleNinja},
+	5: {RoleShogun, RoleSamurai, RoleNinja, RoleNinja, RoleRonin},
+	6: {RoleShogun, RoleSamurai, RoleNinja, RoleNinja, RoleNinja, RoleRonin},
+	7: {RoleShogun, RoleSamurai, RoleSamurai, RoleNinja, RoleNinja, RoleNinja, RoleRonin},
+}
+
+// startingHonor retourne les points d'honneur de départ d'un rôle
+func startingHonor(role Role) int {
+	if role == RoleShogun {
+		return 5
+	}
+	return 3
+}
+
+// TeamOf retourne l'équipe d'un rôle
+func TeamOf(role Role) Team {
+	switch role {
+	case RoleShogun, RoleSamurai:
+		return TeamShogun
+	case RoleNinja:
+		return TeamNinja
+	default:
+		return TeamRonin
+	}
+}
+
+// teamMultiplier retourne le multiplicateur de score d'une équipe
+func teamMultiplier(team Team, playerCount int) int {
+	if team == TeamRonin {
+		if playerCount >= 6 {
+			return 3
+		}
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,782 @@
+package game
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+)
+
+var (
+	ErrGameNotStarted  = errors.New("game is not started")
+	ErrGamePaused      = errors.New("game is paused")
+	ErrNotInGame       = errors.New("player is not in the game")
+	ErrNotYourTurn     = errors.New("not your turn")
+	ErrWrongPhase      = errors.New("command not allowed in this phase")
+	ErrReactionPending = errors.New("a reaction is pending")
+	ErrCardNotInHand   = errors.New("card is not in hand")
+	ErrCardNotPlayable = errors.New("card cannot be played now")
+	ErrInvalidTarget   = errors.New("invalid target")
+	ErrOutOfRange      = errors.New("target is out of range")
+	ErrWeaponLimit     = errors.New("no weapon left to play this turn")
+	ErrInvalidResponse = errors.New("invalid response to reaction")
+	ErrHandLimit       = errors.New("hand is still above the limit")
+	ErrNoAbility       = errors.New("character ability cannot be used now")
+	ErrUnknownCommand  = errors.New("unknown command")
+)
+
+// HandLimit est le nombre maximum de cartes en fin de tour
+const HandLimit = 7
+
+// Phase représente la phase du tour en cours
+type Phase string
+
+const (
+	PhasePlay    Phase = "PLAY"
+	PhaseDiscard Phase = "DISCARD"
+)
+
+// Turn représente le tour en cours
+type Turn struct {
+	Number        int    `json:"number"`
+	Player        string `json:"player"`
+	Phase         Phase  `json:"phase"`
+	WeaponsPlayed int    `json:"weapons_played"`
+}
+
+// ReactionKind représente le type de réponse attendue
+type ReactionKind string
+
+const (
+	ReactionParry     ReactionKind = "PARRY"     // parer une attaque ou subir les dégâts
+	ReactionBattlecry ReactionKind = "BATTLECRY" // défausser une Parade ou perdre 1 point de vie
+	ReactionJujitsu   ReactionKind = "JUJITSU"   // défausser une arme ou perdre 1 point de vie
+)
+
+// Reaction représente une réponse attendue d'un joueur
+type Reaction struct {
+	Kind   ReactionKind `json:"kind"`
+	Player string       `json:"player"`
+	Source string       `json:"source"`
+	Card   Card         `json:"card"`
+	Damage int          `json:"damage"`
+}
+
+// EndReason indique pourquoi une partie s'est terminée
+type EndReason string
+
+const (
+	EndReasonHonor     EndReason = "HONOR"     // un joueur n'a plus de points d'honneur
+	EndReasonIdle      EndReason = "IDLE"      // la partie est restée inactive trop longtemps
+	EndReasonModerator EndReason = "MODERATOR" // un modérateur a mis fin à la partie
+)
+
+// Result représente le résultat d'une partie terminée
+type Result struct {
+	Winner Team            `json:"winner"`
+	Scores map[Team]int    `json:"scores"`
+	Roles  map[string]Role `json:"roles"`
+}
+
+// CommandType représente le type d'une commande de jeu
+type CommandType string
+
+const (
+	CommandPlay    CommandType = "PLAY"     // jouer une carte de sa main
+	CommandRespond CommandType = "RESPOND"  // répondre à la réaction en attente (Card à 0 pour subir)
+	CommandEndTurn CommandType = "END_TURN" // terminer la phase de jeu
+	CommandDiscard CommandType = "DISCARD"  // défausser jusqu'à la limite de main
+	CommandAbility CommandType = "ABILITY"  // utiliser la capacité de son personnage (Nobunaga)
+)
+
+// Command représente une action d'un joueur sur la partie
+type Command struct {
+	Type       CommandType `json:"type"`
+	Player     string      `json:"player"`
+	Card       int         `json:"card,omitempty"`
+	Target     string      `json:"target,omitempty"`
+	TargetCard int         `json:"target_card,omitempty"`
+	Cards      []int       `json:"cards,omitempty"`
+}
+
+// Apply applique une commande de jeu si elle est légale
+func (g *Game) Apply(cmd Command) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.apply(cmd, false); err != nil {
+		return err
+	}
+	g.resetTimeouts(cmd.Player)
+	return nil
+}
+
+// resetTimeouts rend la main au joueur après une action volontaire ou son retour :
+// ses délais dépassés sont oubliés, et le bot qui jouait son siège humain s'efface
+func (g *Game) resetTimeouts(playerName string) {
+	player := g.Players[playerName]
+	if player == nil {
+		return
+	}
+	player.Timeouts = 0
+	player.AFK = false
+	if !player.Bot || player.BotKind != "" {
+		return
+	}
+	player.Bot = false
+	delete(g.bots, playerName)
+	// Le délai armé pour le bot laisse place à celui d'un humain
+	if g.State == GameStateStarted && g.waitingOn() == playerName {
+		g.scheduleDeadline()
+	}
+}
+
+// apply valide et applique une commande, auto indique qu'elle a été jouée par
+// le serveur. Une commande n'est acquittée qu'une fois écrite dans le journal.
+func (g *Game) apply(cmd Command, auto bool) error {
+	return g.change(func() error { return g.applyCommand(cmd, auto) })
+}
+
+// applyCommand valide et applique une commande, verrou tenu
+func (g *Game) applyCommand(cmd Command, auto bool) error {
+	switch g.State {
+	case GameStateStarted:
+	case GameStatePaused:
+		return ErrGamePaused
+	default:
+		return ErrGameNotStarted
+	}
+
+	player, exists := g.Players[cmd.Player]
+	if !exists {
+		return ErrNotInGame
+	}
+
+	// Le générateur dépend de la graine et du nombre de commandes pour rejouer une partie à l'identique
+	g.rng = rand.New(rand.NewSource(g.Seed + int64(g.Commands)))
+
+	var err error
+	if len(g.Reactions) > 0 {
+		if cmd.Type != CommandRespond {
+			return ErrReactionPending
+		}
+		err = g.respond(player, cmd)
+	} else {
+		switch cmd.Type {
+		case CommandPlay:
+			err = g.play(player, cmd)
+		case CommandEndTurn:
+			err = g.endTurn(player)
+		case CommandDiscard:
+			err = g.discardDown(player, cmd.Cards)
+		case CommandAbility:
+			err = g.useAbility(player)
+		case CommandRespond:
+			err = ErrInvalidResponse
+		default:
+			err = ErrUnknownCommand
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	g.Commands++
+	recorded := EventCommand
+	if auto {
+		recorded = EventAutoCommand
+	}
+	if err := g.record(recorded, cmd.Player, cmd); err != nil {
+		return err
+	}
+	// La fin de partie découle de la commande, elle est recréée à la relecture du journal
+	if g.State == GameStateEnded {
+		if err := g.record(EventGameEnded, "", g.Result); err != nil {
+			return err
+		}
+	}
+	g.scheduleDeadline()
+	return nil
+}
+
+// ActivePlayer retourne le joueur dont c'est le tour
+func (g *Game) ActivePlayer() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if g.Turn == nil {
+		return ""
+	}
+	return g.Turn.Player
+}
+
+// WaitingOn retourne le joueur dont la partie attend une action
+func (g *Game) WaitingOn() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.waitingOn()
+}
+
+// PendingReaction retourne la réaction attendue en premier, ou nil
+func (g *Game) PendingReaction() *Reaction {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if len(g.Reactions) == 0 {
+		return nil
+	}
+	reaction := *g.Reactions[0]
+	return &reaction
+}
+
+func (g *Game) waitingOn() string {
+	if len(g.Reactions) > 0 {
+		return g.Reactions[0].Player
+	}
+	if g.Turn == nil {
+		return ""
+	}
+	return g.Turn.Player
+}
+
+// MaxLife retourne les points de vie maximum du joueur
+func (p *Player) MaxLife() int {
+	if p.Character == nil {
+		return 4
+	}
+	return p.Character.Life
+}
+
+// deal distribue rôles, personnages et cartes au lancement de la partie
+func (g *Game) deal() {
+	g.rng = rand.New(rand.NewSource(g.Seed))
+
+	seats := g.seating()
+	roles := append([]Role(nil), rolesByPlayerCount[len(seats)]...)
+	g.rng.Shuffle(len(roles), func(i, j int) { roles[i], roles[j] = roles[j], roles[i] })
+	characters := g.rng.Perm(len(Characters))
+
+	shogun := 0
+	for i, player := range seats {
+		character := Characters[characters[i]]
+		player.Role = roles[i]
+		player.Character = &character
+		player.Life = character.Life
+		player.Honor = startingHonor(player.Role)
+		player.Hand = nil
+		player.InPlay = nil
+		if player.Role == RoleShogun {
+			shogun = i
+		}
+	}
+
+	g.Deck = newDeck()
+	g.rng.Shuffle(len(g.Deck), func(i, j int) { g.Deck[i], g.Deck[j] = g.Deck[j], g.Deck[i] })
+	g.Discard = nil
+
+	// Le Shogun reçoit 4 cartes, puis les joueurs suivants en reçoivent de plus en plus
+	handSizes := []int{4, 5, 5, 6, 6, 7, 7}
+	for i := range seats {
+		player := seats[(shogun+i)%len(seats)]
+		g.draw(player, handSizes[i])
+	}
+
+	g.Turn = &Turn{Number: 1, Player: seats[shogun].Name, Phase: PhasePlay}
+}
+
+// seating retourne les joueurs dans l'ordre des places
+func (g *Game) seating() []*Player {
+	seats := make([]*Player, 0, len(g.Players))
+	for _, player := range g.Players {
+		seats = append(seats, player)
+	}
+	sort.Slice(seats, func(i, j int) bool { return seats[i].Position < seats[j].Position })
+	return seats
+}
+
+// seatIndex retourne l'index d'un joueur autour de la table
+func seatIndex(seats []*Player, name string) int {
+	for i, player := range seats {
+		if player.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// distance retourne la difficulté pour qu'un joueur en attaque un autre
+func (g *Game) distance(from, to *Player) int {
+	seats := g.seating()
+	return seatDistance(seatIndex(seats, from.Name), seatIndex(seats, to.Name), len(seats)) + guard(to.InPlay, to.Character)
+}
+
+// countInPlay compte les propriétés d'un nom donné posées devant un joueur
+func countInPlay(p *Player, name string) int {
+	return countCards(p.InPlay, name)
+}
+
+// weaponLimit retourne le nombre d'armes qu'un joueur peut jouer par tour
+func weaponLimit(p *Player) int {
+	return weaponsPerTurn(p.InPlay, p.Character)
+}
+
+// weaponDamage retourne les dégâts d'une arme jouée par un joueur contre un autre
+func weaponDamage(attacker, target *Player, weapon Card) int {
+	return damageOf(weapon, attacker.InPlay, attacker.Character, target.Character)
+}
+
+// Les règles qui suivent ne dépendent que des cartes posées et du personnage,
+// pour que le moteur et les bots, qui ne voient qu'une vue, les partagent
+
+// countCards compte les cartes d'un nom donné
+func countCards(cards []Card, name string) int {
+	count := 0
+	for _, card := range cards {
+		if card.Name == name {
+			count++
+		}
+	}
+	return count
+}
+
+// embodies indique si un personnage, éventuellement inconnu, est celui donné
+func embodies(character *Character, characterID int) bool {
+	return character != nil && character.ID == characterID
+}
+
+// seatDistance retourne le plus court chemin entre deux places autour d'une table
+func seatDistance(from, to, seats int) int {
+	d := from - to
+	if d < 0 {
+		d = -d
+	}
+	if seats-d < d {
+		d = seats - d
+	}
+	return d
+}
+
+// guard retourne ce que les armures et Enkei ajoutent à la distance d'une cible
+func guard(inPlay []Card, character *Character) int {
+	d := countCards(inPlay, CardArmor)
+	if embodies(character, CharacterEnkei) {
+		d++
+	}
+	return d
+}
+
+// weaponsPerTurn retourne le nombre d'armes jouables par tour
+func weaponsPerTurn(inPlay []Card, character *Character) int {
+	limit := 1 + countCards(inPlay, CardFocus)
+	if embodies(character, CharacterGoemon) {
+		limit++
+	}
+	return limit
+}
+
+// damageOf retourne les dégâts d'une arme selon les cartes et le personnage
+// de l'attaquant et le personnage de la cible
+func damageOf(weapon Card, attackerInPlay []Card, attacker, target *Character) int {
+	damage := weapon.Damage + countCards(attackerInPlay, CardFastDraw)
+	if embodies(attacker, CharacterMusashi) {
+		damage++
+	}
+	if embodies(target, CharacterGinchiyo) && damage > 1 {
+		damage--
+	}
+	return damage
+}
+
+// play joue une carte de la main du joueur actif
+func (g *Game) play(player *Player, cmd Command) error {
+	if g.Turn.Player != player.Name {
+		return ErrNotYourTurn
+	}
+	if g.Turn.Phase != PhasePlay {
+		return ErrWrongPhase
+	}
+	index := findCard(player.Hand, cmd.Card)
+	if index < 0 {
+		return ErrCardNotInHand
+	}
+	card := player.Hand[index]
+
+	var target *Player
+	if cmd.Target != "" {
+		target = g.Players[cmd.Target]
+		if target == nil || target == player {
+			return ErrInvalidTarget
+		}
+	}
+
+	switch {
+	case card.IsWeapon():
+		if target == nil || target.Life <= 0 {
+			return ErrInvalidTarget
+		}
+		if g.Turn.WeaponsPlayed >= weaponLimit(player) {
+			return ErrWeaponLimit
+		}
+		if !player.is(CharacterKojiro) && card.Range < g.distance(player, target) {
+			return ErrOutOfRange
+		}
+		g.Turn.WeaponsPlayed++
+		g.Reactions = append(g.Reactions, &Reaction{
+			Kind:   ReactionParry,
+			Player: target.Name,
+			Source: player.Name,
+			Card:   card,
+			Damage: weaponDamage(player, target, card),
+		})
+
+	case card.Kind == CardKindProperty:
+		player.Hand, _ = removeCard(player.Hand, index)
+		player.InPlay = append(player.InPlay, card)
+		return nil
+
+	case card.Name == CardBattlecry || card.Name == CardJujitsu:
+		kind := ReactionBattlecry
+		if card.Name == CardJujitsu {
+			kind = ReactionJujitsu
+		}
+		seats := g.seating()
+		start := seatIndex(seats, player.Name)
+		for i := 1; i < len(seats); i++ {
+			other := seats[(start+i)%len(seats)]
+			if other.Life <= 0 || other.is(CharacterChiyome) {
+				continue
+			}
+			g.Reactions = append(g.Reactions, &Reaction{
+				Kind:   kind,
+				Player: other.Name,
+				Source: player.Name,
+				Card:   card,
+				Damage: 1,
+			})
+		}
+
+	case card.Name == CardTeaCeremony:
+		// Retirer la carte avant de piocher pour qu'elle parte à la défausse avant un éventuel remélange
+		player.Hand, _ = removeCard(player.Hand, index)
+		g.Discard = append(g.Discard, card)
+		g.draw(player, 3)
+		for _, other := range g.seating() {
+			if other != player {
+				g.draw(other, 1)
+			}
+		}
+		return nil
+
+	case card.Name == CardDiversion:
+		if target == nil || len(target.Hand) == 0 {
+			return ErrInvalidTarget
+		}
+		var stolen Card
+		target.Hand, stolen = removeCard(target.Hand, g.rng.Intn(len(target.Hand)))
+		player.Hand = append(player.Hand, stolen)
+
+	case card.Name == CardGeisha:
+		if target == nil {
+			return ErrInvalidTarget
+		}
+		if cmd.TargetCard != 0 {
+			propertyIndex := findCard(target.InPlay, cmd.TargetCard)
+			if propertyIndex < 0 {
+				return ErrInvalidTarget
+			}
+			var discarded Card
+			target.InPlay, discarded = removeCard(target.InPlay, propertyIndex)
+			g.Discard = append(g.Discard, discarded)
+		} else {
+			if len(target.Hand) == 0 {
+				return ErrInvalidTarget
+			}
+			var discarded Card
+			target.Hand, discarded = removeCard(target.Hand, g.rng.Intn(len(target.Hand)))
+			g.Discard = append(g.Discard, discarded)
+		}
+
+	case card.Name == CardMeditation:
+		player.Hand, _ = removeCard(player.Hand, index)
+		g.Discard = append(g.Discard, card)
+		player.Life = player.MaxLife()
+		if target != nil {
+			g.draw(target, 1)
+		}
+		return nil
+
+	case card.Name == CardDaimyo:
+		player.Hand, _ = removeCard(player.Hand, index)
+		g.Discard = append(g.Discard, card)
+		g.draw(player, 2)
+		return nil
+
+	default:
+		return ErrCardNotPlayable
+	}
+
+	// La carte jouée est retrouvée par son identifiant : Diversion a pu modifier la main
+	player.Hand, _ = removeCard(player.Hand, findCard(player.Hand, card.ID))
+	g.Discard = append(g.Discard, card)
+	return nil
+}
+
+// respond résout la réaction en attente
+func (g *Game) respond(player *Player, cmd Command) error {
+	reaction := *g.Reactions[0]
+	if reaction.Player != player.Name {
+		return ErrNotYourTurn
+	}
+
+	if cmd.Card != 0 {
+		index := findCard(player.Hand, cmd.Card)
+		if index < 0 {
+			return ErrCardNotInHand
+		}
+		if !canAnswer(player, reaction.Kind, player.Hand[index]) {
+			return ErrInvalidResponse
+		}
+		var card Card
+		player.Hand, card = removeCard(player.Hand, index)
+		g.Discard = append(g.Discard, card)
+		g.Reactions = g.Reactions[1:]
+		return nil
+	}
+
+	g.Reactions = g.Reactions[1:]
+	source := g.Players[reaction.Source]
+	g.damage(player, source, reaction.Damage, reaction.Kind == ReactionParry)
+	return nil
+}
+
+// canAnswer indique si une carte permet de répondre à une réaction
+func canAnswer(player *Player, kind ReactionKind, card Card) bool {
+	switch kind {
+	case ReactionParry, ReactionBattlecry:
+		if card.Name == CardParry {
+			return true
+		}
+		// Hanzõ peut parer avec une arme, sauf s'il s'agit de sa dernière carte
+		return player.is(CharacterHanzo) && card.IsWeapon() && len(player.Hand) > 1
+	case ReactionJujitsu:
+		return card.IsWeapon()
+	}
+	return false
+}
+
+// damage inflige des dégâts et transfère l'honneur si la victime devient inoffensive
+func (g *Game) damage(victim, source *Player, amount int, byWeapon bool) {
+	if victim.Life <= 0 {
+		return
+	}
+	lost := amount
+	if lost > victim.Life {
+		lost = victim.Life
+	}
+	victim.Life -= lost
+
+	if byWeapon {
+		if victim.is(CharacterUshiwaka) {
+			g.draw(victim, lost)
+		}
+		if source != nil && source.is(CharacterTomoe) {
+			g.draw(source, 1)
+		}
+	}
+
+	if victim.Life == 0 && g.State == GameStateStarted {
+		victim.Honor--
+		if source != nil {
+			source.Honor++
+		}
+		g.checkEnd()
+	}
+}
+
+// draw fait piocher des cartes, en remélangeant la défausse quand le paquet est épuisé
+func (g *Game) draw(player *Player, count int) {
+	for i := 0; i < count; i++ {
+		if len(g.Deck) == 0 {
+			if len(g.Discard) == 0 {
+				return
+			}
+			// Chaque épuisement du paquet coûte 1 point d'honneur à tous les joueurs
+			g.Exhaustions++
+			for _, other := range g.Players {
+				other.Honor--
+			}
+			g.Deck, g.Discard = g.Discard, nil
+			g.rng.Shuffle(len(g.Deck), func(i, j int) { g.Deck[i], g.Deck[j] = g.Deck[j], g.Deck[i] })
+			g.checkEnd()
+		}
+		player.Hand = append(player.Hand, g.Deck[0])
+		g.Deck = g.Deck[1:]
+	}
+}
+
+// useAbility applique la capacité de Nobunaga : pendant sa phase de jeu, il
+// perd 1 point de vie, sauf son dernier, pour piocher 1 carte
+func (g *Game) useAbility(player *Player) error {
+	if g.Turn.Player != player.Name {
+		return ErrNotYourTurn
+	}
+	if g.Turn.Phase != PhasePlay {
+		return ErrWrongPhase
+	}
+	if !player.is(CharacterNobunaga) || player.Life <= 1 {
+		return ErrNoAbility
+	}
+	player.Life--
+	g.draw(player, 1)
+	return nil
+}
+
+// endTurn termine la phase de jeu du joueur actif
+func (g *Game) endTurn(player *Player) error {
+	if g.Turn.Player != player.Name {
+		return ErrNotYourTurn
+	}
+	if g.Turn.Phase != PhasePlay {
+		return ErrWrongPhase
+	}
+	if len(player.Hand) > HandLimit {
+		g.Turn.Phase = PhaseDiscard
+		return nil
+	}
+	g.nextTurn()
+	return nil
+}
+
+// discardDown défausse les cartes choisies pour revenir à la limite de main
+func (g *Game) discardDown(player *Player, ids []int) error {
+	if g.Turn.Player != player.Name {
+		return ErrNotYourTurn
+	}
+	if g.Turn.Phase != PhaseDiscard {
+		return ErrWrongPhase
+	}
+
+	hand := append([]Card(nil), player.Hand...)
+	discarded := make([]Card, 0, len(ids))
+	for _, id := range ids {
+		index := findCard(hand, id)
+		if index < 0 {
+			return ErrCardNotInHand
+		}
+		var card Card
+		hand, card = removeCard(hand, index)
+		discarded = append(discarded, card)
+	}
+	if len(hand) > HandLimit {
+		return ErrHandLimit
+	}
+
+	player.Hand = hand
+	g.Discard = append(g.Discard, discarded...)
+	g.nextTurn()
+	return nil
+}
+
+// nextTurn passe au joueur suivant, qui récupère puis pioche
+func (g *Game) nextTurn() {
+	if g.State == GameStateEnded {
+		return
+	}
+	seats := g.seating()
+	next := seats[(seatIndex(seats, g.Turn.Player)+1)%len(seats)]
+	g.Turn = &Turn{Number: g.Turn.Number + 1, Player: next.Name, Phase: PhasePlay}
+
+	// Un joueur inoffensif récupère tous ses points de vie au début de son tour
+	if next.Life <= 0 {
+		next.Life = next.MaxLife()
+	}
+
+	count := 2
+	if next.is(CharacterHideyoshi) {
+		count++
+	}
+	// Ieyasu prend la carte du dessus de la défausse à la place de sa première pioche
+	if next.is(CharacterIeyasu) && len(g.Discard) > 0 {
+		top := len(g.Discard) - 1
+		next.Hand = append(next.Hand, g.Discard[top])
+		g.Discard = g.Discard[:top]
+		count--
+	}
+	g.draw(next, count)
+}
+
+// checkEnd termine la partie dès qu'un joueur n'a plus d'honneur
+func (g *Game) checkEnd() {
+	if g.State != GameStateStarted {
+		return
+	}
+	for _, player := range g.Players {
+		if player.Honor <= 0 {
+			g.endGame()
+			return
+		}
+	}
+}
+
+// endGame calcule les scores d'équipe et révèle les rôles
+func (g *Game) endGame() {
+	g.State = GameStateEnded
+	g.EndReason = EndReasonHonor
+	g.Reactions = nil
+	g.stopTimer()
+
+	result := &Result{
+		Scores: make(map[Team]int),
+		Roles:  make(map[string]Role),
+	}
+	sizes := make(map[Team]int)
+	for name, player := range g.Players {
+		team := TeamOf(player.Role)
+		result.Scores[team] += player.Honor * teamMultiplier(team, len(g.Players))
+		result.Roles[name] = player.Role
+		sizes[team]++
+	}
+
+	// En cas d'égalité, l'équipe la moins nombreuse l'emporte ; à effectif
+	// égal, les Ninjas l'emportent sur le camp du Shogun
+	for _, team := range []Team{TeamRonin, TeamNinja, TeamShogun} {
+		score, exists := result.Scores[team]
+		if !exists {
+			continue
+		}
+		best := result.Scores[result.Winner]
+		if result.Winner == "" || score > best || (score == best && sizes[team] < sizes[result.Winner]) {
+			result.Winner = team
+		}
+	}
+	g.Result = result
+}
+
+// Hand retourne une copie de la main d'un joueur
+func (g *Game) Hand(playerName string) []Card {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	player, exists := g.Players[playerName]
+	if !exists {
+		return nil
+	}
+	return append([]Card{}, player.Hand...)
+}
+
+// Abort termine une partie en cours sans vainqueur
+func (g *Game) Abort(reason EndReason) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.State != GameStateStarted && g.State != GameStatePaused {
+		return false
+	}
+	return g.change(func() error { return g.abort(reason) }) == nil
+}
+
+// abort termine la partie sans vainqueur, le verrou doit être tenu
+func (g *Game) abort(reason EndReason) error {
+	g.stopTimer()
+	g.State = GameStateEnded
+	g.EndReason = reason
+	g.Reactions = nil
+	return g.record(EventGameEnded, "", reason)
+}
@@ -0,0 +1,276 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// newTestGame lance une partie sans délais de jeu entre des joueurs nommés p1, p2…
+func newTestGame(t testing.TB, players int, seed int64) *Game {
+	t.Helper()
+	g := NewGame("test")
+	g.Rules = Ruleset{}
+	g.Seed = seed
+	for i := 1; i <= players; i++ {
+		g.AddPlayer(NewPlayer(fmt.Sprintf("p%d", i), 0))
+	}
+	if err := g.StartGame(); err != nil {
+		t.Fatalf("game with %d players did not start: %v", players, err)
+	}
+	return g
+}
+
+// randomCommands propose dans le désordre des commandes plausibles du joueur
+// attendu : des cartes de sa main sur des cibles et des cartes au hasard
+func randomCommands(g *Game, rng *rand.Rand) []Command {
+	player := g.Players[g.waitingOn()]
+	seats := g.seating()
+	var commands []Command
+	switch {
+	case len(g.Reactions) > 0:
+		commands = append(commands, Command{Type: CommandRespond})
+		for _, card := range player.Hand {
+			commands = append(commands, Command{Type: CommandRespond, Card: card.ID})
+		}
+	case g.Turn.Phase == PhaseDiscard:
+		for i := 0; i < 3; i++ {
+			cards := make([]int, 0, len(player.Hand))
+			for _, index := range rng.Perm(len(player.Hand))[:rng.Intn(len(player.Hand)+1)] {
+				cards = append(cards, player.Hand[index].ID)
+			}
+			commands = append(commands, Command{Type: CommandDiscard, Cards: cards})
+		}
+	default:
+		commands = append(commands, Command{Type: CommandEndTurn}, Command{Type: CommandAbility})
+		for _, card := range player.Hand {
+			target := seats[rng.Intn(len(seats))]
+			cmd := Command{Type: CommandPlay, Card: card.ID, Target: target.Name}
+			if len(target.InPlay) > 0 {
+				cmd.TargetCard = target.InPlay[rng.Intn(len(target.InPlay))].ID
+			}
+			commands = append(commands, cmd, Command{Type: CommandPlay, Card: card.ID})
+		}
+	}
+	rng.Shuffle(len(commands), func(i, j int) { commands[i], commands[j] = commands[j], commands[i] })
+	for i := range commands {
+		commands[i].Player = player.Name
+	}
+	return commands
+}
+
+// playRandomGame joue une partie avec des commandes tirées au hasard en
+// vérifiant les invariants après chaque commande
+func playRandomGame(t testing.TB, players int, seed int64) *Game {
+	t.Helper()
+	g := newTestGame(t, players, seed)
+	honor := totalHonor(g)
+	checkInvariants(t, g, honor)
+
+	rng := rand.New(rand.NewSource(seed))
+	for step := 0; g.State == GameStateStarted; step++ {
+		if step > DefaultMaxCommands {
+			t.Fatalf("seed %d: game did not end after %d commands", seed, step)
+		}
+		checkOnlyWaitingPlayerActs(t, g, rng)
+
+		applied := false
+		for _, cmd := range randomCommands(g, rng) {
+			before := state(g)
+			if err := g.Apply(cmd); err != nil {
+				if after := state(g); after != before {
+					t.Fatalf("seed %d: rejected %+v (%v) changed the game", seed, cmd, err)
+				}
+				continue
+			}
+			applied = true
+			break
+		}
+		if !applied {
+			player := g.Players[g.waitingOn()]
+			if err := g.Apply(g.defaultCommand(player)); err != nil {
+				t.Fatalf("seed %d: default command of %s rejected: %v", seed, player.Name, err)
+			}
+		}
+		checkInvariants(t, g, honor)
+	}
+	return g
+}
+
+// checkOnlyWaitingPlayerActs vérifie qu'un autre joueur que celui attendu ne peut pas agir
+func checkOnlyWaitingPlayerActs(t testing.TB, g *Game, rng *rand.Rand) {
+	t.Helper()
+	seats := g.seating()
+	other := seats[rng.Intn(len(seats))]
+	if other.Name == g.waitingOn() {
+		return
+	}
+	for _, cmd := range []Command{
+		{Type: CommandEndTurn, Player: other.Name},
+		{Type: CommandRespond, Player: other.Name},
+		{Type: CommandDiscard, Player: other.Name},
+	} {
+		if err := g.Apply(cmd); err == nil {
+			t.Fatalf("%s acted with %s while the game waits on %s", other.Name, cmd.Type, g.waitingOn())
+		}
+	}
+}
+
+// checkInvariants vérifie l'état de la partie après une commande
+func checkInvariants(t testing.TB, g *Game, initialHonor int) {
+	t.Helper()
+
+	// Conservation des cartes : chaque carte du paquet est dans une seule pile
+	seen := make(map[int]string, DeckSize())
+	add := func(pile string, cards []Card) {
+		for _, card := range cards {
+			if where, exists := seen[card.ID]; exists {
+				t.Fatalf("card %d is both in %s and %s", card.ID, where, pile)
+			}
+			if _, exists := cardByID(card.ID); !exists {
+				t.Fatalf("unknown card %d in %s", card.ID, pile)
+			}
+			seen[card.ID] = pile
+		}
+	}
+	add("deck", g.Deck)
+	add("discard", g.Discard)
+	for _, player := range g.Players {
+		add(player.Name+" hand", player.Hand)
+		add(player.Name+" in play", player.InPlay)
+	}
+	if len(seen) != DeckSize() {
+		t.Fatalf("%d cards in the game, want %d", len(seen), DeckSize())
+	}
+
+	// L'honneur ne fait que passer d'un joueur à l'autre, sauf à l'épuisement du paquet
+	if want := initialHonor - g.Exhaustions*len(g.Players); totalHonor(g) != want {
+		t.Fatalf("total honor %d after %d exhaustions, want %d", totalHonor(g), g.Exhaustions, want)
+	}
+
+	lowHonor := false
+	for _, player := range g.Players {
+		if player.Life < 0 || player.Life > player.MaxLife() {
+			t.Fatalf("%s has %d life, max %d", player.Name, player.Life, player.MaxLife())
+		}
+		if player.Honor <= 0 {
+			lowHonor = true
+		}
+	}
+
+	switch g.State {
+	case GameStateStarted:
+		if lowHonor {
+			t.Fatal("a player has no honor left but the game goes on")
+		}
+		if g.Turn == nil || g.Players[g.Turn.Player] == nil {
+			t.Fatalf("no active player: %+v", g.Turn)
+		}
+		if g.Players[g.waitingOn()] == nil {
+			t.Fatalf("game waits on unknown player %q", g.waitingOn())
+		}
+	case GameStateEnded:
+		if !lowHonor || g.Result == nil || g.EndReason != EndReasonHonor {
+			t.Fatalf("game ended without a player out of honor: %+v", g.Result)
+		}
+	default:
+		t.Fatalf("unexpected state %s", g.State)
+	}
+}
+
+func totalHonor(g *Game) int {
+	total := 0
+	for _, player := range g.Players {
+		total += player.Honor
+	}
+	return total
+}
+
+// state sérialise l'état observable de la partie pour la comparer, sans le
+// journal dont seq suffit à détecter un nouvel événement
+func state(g *Game) string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	snapshot := g.snapshot()
+	snapshot.Events = nil
+	data, _ := json.Marshal(snapshot)
+	return string(data)
+}
+
+func TestRandomGamesKeepInvariants(t *testing.T) {
+	games := 60
+	if testing.Short() {
+		games = 10
+	}
+	for seed := int64(1); seed <= int64(games); seed++ {
+		players := MinPlayers + int(seed)%(7-MinPlayers+1)
+		g := playRandomGame(t, players, seed)
+		if err := g.Apply(Command{Type: CommandEndTurn, Player: g.Turn.Player}); err != ErrGameNotStarted {
+			t.Fatalf("seed %d: command after the end: got %v, want %v", seed, err, ErrGameNotStarted)
+		}
+	}
+}
+
+func TestBotGamesKeepInvariants(t *testing.T) {
+	for seed := int64(1); seed <= 20; seed++ {
+		g := newTestGame(t, MinPlayers+int(seed)%5, seed)
+		g.manualBots = true
+		for _, player := range g.Players {
+			player.Bot, player.BotKind = true, BotNormal
+		}
+		honor := totalHonor(g)
+		for g.State == GameStateStarted && g.Commands < DefaultMaxCommands {
+			player := g.Players[g.waitingOn()]
+			g.mu.Lock()
+			cmd := g.botCommand(player)
+			g.mu.Unlock()
+			if err := g.Apply(cmd); err != nil {
+				t.Fatalf("seed %d: bot %s played an illegal %+v: %v", seed, player.Name, cmd, err)
+			}
+			checkInvariants(t, g, honor)
+		}
+		if g.State != GameStateEnded {
+			t.Fatalf("seed %d: bot game did not end", seed)
+		}
+	}
+}
+
+// FuzzRandomGame joue des parties à partir de graines quelconques
+func FuzzRandomGame(f *testing.F) {
+	f.Add(int64(0), uint8(3))
+	f.Add(int64(42), uint8(5))
+	f.Add(int64(-7), uint8(7))
+	f.Fuzz(func(t *testing.T, seed int64, players uint8) {
+		playRandomGame(t, MinPlayers+int(players)%(7-MinPlayers+1), seed)
+	})
+}
+
+// FuzzApplyCommand applique une commande décodée d'un JSON quelconque, comme
+// celles reçues par l'API, après quelques coups de bots : elle ne doit ni
+// paniquer ni casser les invariants
+func FuzzApplyCommand(f *testing.F) {
+	f.Add(int64(1), uint8(0), []byte(`{"type":"END_TURN","player":"p1"}`))
+	f.Add(int64(2), uint8(10), []byte(`{"type":"PLAY","player":"p2","card":7,"target":"p3","target_card":12}`))
+	f.Add(int64(3), uint8(40), []byte(`{"type":"DISCARD","player":"p1","cards":[1,1,2,-5]}`))
+	f.Add(int64(4), uint8(25), []byte(`{"type":"RESPOND","player":"p4","card":999999}`))
+	f.Fuzz(func(t *testing.T, seed int64, steps uint8, data []byte) {
+		var cmd Command
+		if json.Unmarshal(data, &cmd) != nil {
+			return
+		}
+		g := newTestGame(t, MinPlayers+int(uint64(seed)%5), seed)
+		honor := totalHonor(g)
+		for i := 0; i < int(steps) && g.State == GameStateStarted; i++ {
+			g.mu.Lock()
+			g.apply(g.botCommand(g.Players[g.waitingOn()]), true)
+			g.mu.Unlock()
+		}
+
+		before := state(g)
+		if err := g.Apply(cmd); err != nil && state(g) != before {
+			t.Fatalf("rejected %+v (%v) changed the game", cmd, err)
+		}
+		checkInvariants(t, g, honor)
+	})
+}
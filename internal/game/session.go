@@ -0,0 +1,129 @@
+package game
+
+import "time"
+
+// MarkDisconnected réserve le siège d'un joueur dont la connexion est tombée
+func (g *Game) MarkDisconnected(playerName string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	player, exists := g.Players[playerName]
+	if !exists || player.DisconnectedAt != nil || g.State == GameStateEnded {
+		return false
+	}
+	return g.change(func() error {
+		now := g.now()
+		player.DisconnectedAt = &now
+		return g.record(EventPlayerDisconnected, playerName, nil)
+	}) == nil
+}
+
+// Reconnect rend son siège à un joueur revenu, et relance la partie si elle
+// n'attendait que lui ; un joueur exclu ne le retrouve pas
+func (g *Game) Reconnect(playerName string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	player, exists := g.Players[playerName]
+	if !exists || player.DisconnectedAt == nil || player.Kicked {
+		return false
+	}
+	return g.change(func() error {
+		autoplayed := player.autoplayed()
+		player.DisconnectedAt = nil
+		player.Abandoned = false
+		if err := g.record(EventPlayerReconnected, playerName, nil); err != nil {
+			return err
+		}
+		g.resetTimeouts(playerName)
+		if g.State == GameStatePaused && g.PauseReason == PauseReasonDisconnect && g.waitingOn() == playerName {
+			return g.resume()
+		}
+		// Le délai armé pour le serveur laisse place à celui du joueur revenu
+		if autoplayed && g.State == GameStateStarted && g.waitingOn() == playerName {
+			g.scheduleDeadline()
+		}
+		return nil
+	}) == nil
+}
+
+// ReleaseSeat libère le siège d'un joueur resté déconnecté plus longtemps que grace :
+// il quitte un salon en attente, ou son siège est marqué abandonné dans une partie lancée
+func (g *Game) ReleaseSeat(playerName string, grace time.Duration) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	player, exists := g.Players[playerName]
+	if !exists || player.DisconnectedAt == nil || player.Abandoned || time.Since(*player.DisconnectedAt) < grace {
+		return false
+	}
+	return g.change(func() error { return g.releaseSeat(player) }) == nil
+}
+
+// Kick exclut un joueur sans attendre de période de grâce : il quitte un salon
+// en attente, ou perd définitivement son siège dans une partie lancée
+func (g *Game) Kick(playerName string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	player, exists := g.Players[playerName]
+	if !exists || player.Kicked || g.State == GameStateEnded {
+		return false
+	}
+	return g.change(func() error {
+		if player.DisconnectedAt == nil {
+			now := g.now()
+			player.DisconnectedAt = &now
+			if err := g.record(EventPlayerDisconnected, playerName, nil); err != nil {
+				return err
+			}
+		}
+		if g.State != GameStateWaiting {
+			player.Kicked = true
+			if err := g.record(EventPlayerKicked, playerName, nil); err != nil {
+				return err
+			}
+			if player.Abandoned {
+				return nil
+			}
+		}
+		return g.releaseSeat(player)
+	}) == nil
+}
+
+// IsKicked indique si un joueur a été exclu de la partie
+func (g *Game) IsKicked(playerName string) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	player, exists := g.Players[playerName]
+	return exists && player.Kicked
+}
+
+// releaseSeat libère le siège d'un joueur déconnecté, verrou tenu
+func (g *Game) releaseSeat(player *Player) error {
+	playerName := player.Name
+	if g.State == GameStateWaiting {
+		return g.removePlayer(playerName)
+	}
+	player.Abandoned = true
+	if err := g.record(EventSeatReleased, playerName, nil); err != nil {
+		return err
+	}
+
+	// Le serveur joue désormais ce siège : une pause due à sa déconnexion n'a plus lieu d'être
+	if g.waitingOn() == playerName {
+		if g.State == GameStatePaused && g.PauseReason == PauseReasonDisconnect {
+			return g.resume()
+		}
+		g.scheduleDeadline()
+	}
+	return nil
+}
+
+// HasPlayer indique si un joueur occupe un siège de la partie
+func (g *Game) HasPlayer(playerName string) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	_, exists := g.Players[playerName]
+	return exists
+}
@@ -0,0 +1,82 @@
+package game
+
+import (
+	"fmt"
+	"testing"
+)
+
+// loggedGame lance une partie journalisée dès sa création, sans délais de jeu ;
+// les sièges joués par le serveur n'agissent pas pendant le test, pas plus que
+// dans une copie de la partie relue depuis le journal
+func loggedGame(t *testing.T, players int) (*Game, *memoryLog) {
+	t.Helper()
+	log := &memoryLog{}
+	g := NewGame("p1")
+	g.Rules = Ruleset{BotDelaySeconds: 3600}
+	g.commandLog = log
+	g.manualBots = true
+	if err := g.recordCreation(); err != nil {
+		t.Fatalf("recordCreation: %v", err)
+	}
+	for i := 1; i <= players; i++ {
+		if err := g.AddPlayer(NewPlayer(fmt.Sprintf("p%d", i), 0)); err != nil {
+			t.Fatalf("AddPlayer: %v", err)
+		}
+	}
+	if err := g.StartGame(); err != nil {
+		t.Fatalf("StartGame: %v", err)
+	}
+	return g, log
+}
+
+func TestKickedPlayerCannotReclaimSeat(t *testing.T) {
+	g, log := loggedGame(t, 4)
+
+	// Un siège libéré après la période de grâce revient à son joueur
+	if !g.MarkDisconnected("p3") || !g.ReleaseSeat("p3", 0) {
+		t.Fatal("p3's seat was not released")
+	}
+	if !g.Reconnect("p3") {
+		t.Fatal("p3 did not get their released seat back")
+	}
+
+	// Un joueur exclu le perd pour de bon
+	if !g.Kick("p2") {
+		t.Fatal("p2 was not kicked")
+	}
+	if g.Kick("p2") {
+		t.Fatal("p2 was kicked twice")
+	}
+	if !g.IsKicked("p2") || !g.Players["p2"].Abandoned {
+		t.Fatal("p2's seat is not released after the kick")
+	}
+	if g.Reconnect("p2") {
+		t.Fatal("a kicked player got their seat back")
+	}
+
+	// L'exclusion survit à la relecture du journal
+	entries, err := log.Load(g.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := ReplayGame(entries)
+	if err != nil {
+		t.Fatalf("ReplayGame: %v", err)
+	}
+	if !replayed.IsKicked("p2") || replayed.IsKicked("p3") {
+		t.Fatal("the replayed game lost track of who was kicked")
+	}
+	if replayed.Reconnect("p2") {
+		t.Fatal("a kicked player got their seat back after a restart")
+	}
+}
+
+func TestKickFromWaitingGameRemovesPlayer(t *testing.T) {
+	g := NewGame("p1")
+	for i := 1; i <= 2; i++ {
+		g.AddPlayer(NewPlayer(fmt.Sprintf("p%d", i), 0))
+	}
+	if !g.Kick("p2") || g.HasPlayer("p2") {
+		t.Fatal("p2 is still seated at the waiting table")
+	}
+}
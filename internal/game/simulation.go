@@ -0,0 +1,162 @@
+package game
+
+import (
+	"errors"
+	"math/rand"
+	"strconv"
+)
+
+var (
+	ErrInvalidPlayerCount = errors.New("invalid player count")
+	ErrUnknownCharacter   = errors.New("unknown character")
+)
+
+// DefaultMaxCommands borne une partie simulée qui ne se termine pas
+const DefaultMaxCommands = 5000
+
+// SimulationConfig décrit une partie jouée uniquement par des bots, sans
+// HTTP ni délais de jeu
+type SimulationConfig struct {
+	Players     int      // nombre de sièges, de MinPlayers à 7
+	Bots        []string // sorte de bot de chaque siège, répétée si la liste est plus courte
+	Characters  []int    // personnages imposés, donnés à des sièges tirés au hasard
+	Seed        int64    // graine de la partie, une même graine rejoue la même partie
+	MaxCommands int      // nombre de commandes au-delà duquel la partie est abandonnée
+}
+
+// SeatResult décrit un siège à la fin d'une partie simulée
+type SeatResult struct {
+	Name      string `json:"name"`
+	Order     int    `json:"order"` // rang de jeu à partir du Shogun, qui joue en 1
+	Bot       string `json:"bot"`
+	Role      Role   `json:"role"`
+	Team      Team   `json:"team"`
+	Character string `json:"character"`
+	Honor     int    `json:"honor"`
+	Won       bool   `json:"won"`
+}
+
+// SimulationResult résume une partie simulée
+type SimulationResult struct {
+	Seed        int64        `json:"seed"`
+	Ended       bool         `json:"ended"` // faux si MaxCommands a été atteint
+	Winner      Team         `json:"winner,omitempty"`
+	Turns       int          `json:"turns"`
+	Commands    int          `json:"commands"`
+	Exhaustions int          `json:"exhaustions"`
+	Seats       []SeatResult `json:"seats"`
+}
+
+// Simulate joue une partie complète entre bots et retourne son résultat
+func Simulate(config SimulationConfig) (*SimulationResult, error) {
+	if _, exists := rolesByPlayerCount[config.Players]; !exists {
+		return nil, ErrInvalidPlayerCount
+	}
+	if len(config.Characters) > config.Players {
+		return nil, ErrInvalidPlayerCount
+	}
+	for _, id := range config.Characters {
+		if id < 1 || id > len(Characters) {
+			return nil, ErrUnknownCharacter
+		}
+	}
+	bots := config.Bots
+	if len(bots) == 0 {
+		bots = []string{BotHeuristic}
+	}
+	maxCommands := config.MaxCommands
+	if maxCommands <= 0 {
+		maxCommands = DefaultMaxCommands
+	}
+
+	// Sans délais ni dépôt, la partie ne vit que le temps de la simulation
+	g := NewGame("simulator")
+	g.ID = "simulation-" + strconv.FormatInt(config.Seed, 10)
+	g.Seed = config.Seed
+	g.Rules = Ruleset{}
+	g.manualBots = true
+	for i := 0; i < config.Players; i++ {
+		if _, err := g.AddBot(bots[i%len(bots)]); err != nil {
+			return nil, err
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.startGame()
+	g.imposeCharacters(config.Characters)
+	for g.State == GameStateStarted && g.Commands < maxCommands {
+		player := g.Players[g.waitingOn()]
+		if g.apply(g.botCommand(player), true) != nil && g.apply(g.defaultCommand(player), true) != nil {
+			break
+		}
+	}
+	return g.simulationResult(), nil
+}
+
+// imposeCharacters donne les personnages demandés à des sièges tirés au
+// hasard, en échangeant avec le siège qui les avait reçus, le verrou doit être tenu
+func (g *Game) imposeCharacters(ids []int) {
+	if len(ids) == 0 {
+		return
+	}
+	seats := g.seating()
+	rng := rand.New(rand.NewSource(g.Seed))
+	order := rng.Perm(len(seats))
+	for i, id := range ids {
+		character := Characters[id-1]
+		seat := seats[order[i]]
+		for _, other := range seats {
+			if other != seat && other.is(id) {
+				swapped := *seat.Character
+				other.Character = &swapped
+				other.Life = swapped.Life
+			}
+		}
+		seat.Character = &character
+		seat.Life = character.Life
+	}
+}
+
+// simulationResult résume la partie, le verrou doit être tenu
+func (g *Game) simulationResult() *SimulationResult {
+	result := &SimulationResult{
+		Seed:        g.Seed,
+		Ended:       g.Result != nil,
+		Commands:    g.Commands,
+		Exhaustions: g.Exhaustions,
+	}
+	if g.Result != nil {
+		result.Winner = g.Result.Winner
+	}
+	if g.Turn != nil {
+		result.Turns = g.Turn.Number
+	}
+
+	seats := g.seating()
+	shogun := 0
+	for i, player := range seats {
+		if player.Role == RoleShogun {
+			shogun = i
+		}
+	}
+	for i := range seats {
+		player := seats[(shogun+i)%len(seats)]
+		team := TeamOf(player.Role)
+		seat := SeatResult{
+			Name:  player.Name,
+			Order: i + 1,
+			Bot:   player.BotKind,
+			Role:  player.Role,
+			Team:  team,
+			Honor: player.Honor,
+			Won:   result.Ended && team == result.Winner,
+		}
+		if player.Character != nil {
+			seat.Character = player.Character.Name
+		}
+		result.Seats = append(result.Seats, seat)
+	}
+	return result
+}
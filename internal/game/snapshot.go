@@ -0,0 +1,184 @@
+package game
+
+import (
+	"errors"
+	"time"
+)
+
+// PlayerSnapshot représente un joueur avec ses informations cachées
+type PlayerSnapshot struct {
+	Player
+	Role Role   `json:"role"`
+	Hand []Card `json:"hand"`
+}
+
+// Snapshot représente l'état complet d'une partie, informations cachées comprises
+type Snapshot struct {
+	ID          string                     `json:"id"`
+	State       GameState                  `json:"state"`
+	Players     map[string]*PlayerSnapshot `json:"players"`
+	CreatedBy   string                     `json:"created_by"`
+	CreatedAt   time.Time                  `json:"created_at"`
+	UpdatedAt   time.Time                  `json:"updated_at"`
+	MaxPlayers  int                        `json:"max_players"`
+	Turn        *Turn                      `json:"turn,omitempty"`
+	Reactions   []*Reaction                `json:"reactions,omitempty"`
+	Exhaustions int                        `json:"exhaustions"`
+	Result      *Result                    `json:"result,omitempty"`
+	EndReason   EndReason                  `json:"end_reason,omitempty"`
+	PausedAt    *time.Time                 `json:"paused_at,omitempty"`
+	PauseReason PauseReason                `json:"pause_reason,omitempty"`
+	PauseVotes  map[string]bool            `json:"pause_votes,omitempty"`
+	Seq         int64                      `json:"seq"`
+	Rules       Ruleset                    `json:"rules"`
+	Seed        int64                      `json:"seed"`
+	Commands    int                        `json:"commands"`
+	Deck        []Card                     `json:"deck"`
+	Discard     []Card                     `json:"discard"`
+	Events      []Event                    `json:"events"`
+}
+
+// Snapshot retourne une copie complète de l'état de la partie
+func (g *Game) Snapshot() *Snapshot {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.snapshot()
+}
+
+// snapshot copie l'état de la partie, le verrou doit être tenu
+func (g *Game) snapshot() *Snapshot {
+	snapshot := &Snapshot{
+		ID:          g.ID,
+		State:       g.State,
+		Players:     make(map[string]*PlayerSnapshot, len(g.Players)),
+		CreatedBy:   g.CreatedBy,
+		CreatedAt:   g.CreatedAt,
+		UpdatedAt:   g.UpdatedAt,
+		MaxPlayers:  g.MaxPlayers,
+		Exhaustions: g.Exhaustions,
+		Result:      g.Result,
+		EndReason:   g.EndReason,
+		PausedAt:    g.PausedAt,
+		PauseReason: g.PauseReason,
+		Seq:         g.Seq,
+		Rules:       g.Rules,
+		Seed:        g.Seed,
+		Commands:    g.Commands,
+		Deck:        append([]Card(nil), g.Deck...),
+		Discard:     append([]Card(nil), g.Discard...),
+		Events:      append([]Event(nil), g.events...),
+	}
+	if g.Turn != nil {
+		turn := *g.Turn
+		snapshot.Turn = &turn
+	}
+	for _, reaction := range g.Reactions {
+		r := *reaction
+		snapshot.Reactions = append(snapshot.Reactions, &r)
+	}
+	if g.PauseVotes != nil {
+		snapshot.PauseVotes = make(map[string]bool, len(g.PauseVotes))
+		for name, vote := range g.PauseVotes {
+			snapshot.PauseVotes[name] = vote
+		}
+	}
+	for name, player := range g.Players {
+		copied := *player
+		copied.InPlay = append([]Card(nil), player.InPlay...)
+		copied.Hand = nil
+		snapshot.Players[name] = &PlayerSnapshot{
+			Player: copied,
+			Role:   player.Role,
+			Hand:   append([]Card(nil), player.Hand...),
+		}
+	}
+	return snapshot
+}
+
+// RestoreGame reconstruit une partie à partir d'un snapshot et réarme son délai en cours
+func RestoreGame(snapshot *Snapshot, repository GameRepository) *Game {
+	g := &Game{repository: repository}
+
+	g.mu.Lock()
+	g.restore(snapshot)
+	g.scheduleDeadline()
+	g.mu.Unlock()
+	return g
+}
+
+// restore remet la partie dans l'état d'un snapshot, qu'elle garde sans le copier, le verrou doit être tenu
+func (g *Game) restore(snapshot *Snapshot) {
+	g.ID = snapshot.ID
+	g.State = snapshot.State
+	g.Players = make(map[string]*Player, len(snapshot.Players))
+	g.CreatedBy = snapshot.CreatedBy
+	g.CreatedAt = snapshot.CreatedAt
+	g.UpdatedAt = snapshot.UpdatedAt
+	g.MaxPlayers = snapshot.MaxPlayers
+	g.Turn = snapshot.Turn
+	g.Reactions = snapshot.Reactions
+	g.Exhaustions = snapshot.Exhaustions
+	g.Result = snapshot.Result
+	g.EndReason = snapshot.EndReason
+	g.PausedAt = snapshot.PausedAt
+	g.PauseReason = snapshot.PauseReason
+	g.PauseVotes = snapshot.PauseVotes
+	g.Seq = snapshot.Seq
+	g.Rules = snapshot.Rules
+	g.Seed = snapshot.Seed
+	g.Commands = snapshot.Commands
+	g.Deck = snapshot.Deck
+	g.Discard = snapshot.Discard
+	g.events = snapshot.Events
+	for name, saved := range snapshot.Players {
+		player := saved.Player
+		player.Role = saved.Role
+		player.Hand = saved.Hand
+		g.Players[name] = &player
+	}
+}
+
+// checkpoint retient l'état d'une partie avant un changement journalisé
+type checkpoint struct {
+	snapshot       *Snapshot
+	spectatorViews []*GameView
+	deadline       *time.Time
+	remaining      time.Duration
+}
+
+// change applique un changement qui enregistre des événements, le verrou doit
+// être tenu. Le changement n'est acquis qu'une fois tous ses événements écrits
+// dans le journal : si l'un d'eux ne l'est pas, la partie revient à son état
+// d'avant et l'erreur est retournée, pour que le journal ne garde pas de trou.
+func (g *Game) change(mutate func() error) error {
+	if g.commandLog == nil || g.replaying {
+		return mutate()
+	}
+	before := checkpoint{
+		snapshot:       g.snapshot(),
+		spectatorViews: g.spectatorViews,
+		deadline:       g.Deadline,
+		remaining:      g.remaining,
+	}
+	err := mutate()
+	if errors.Is(err, ErrLogWrite) {
+		g.rollback(before)
+	}
+	return err
+}
+
+// rollback annule un changement dont un événement n'a pas pu être journalisé,
+// le verrou doit être tenu. Les bots, qui ont pu observer l'événement annulé,
+// sont recréés à partir de l'historique restauré, et le délai en cours reprend
+// là où il en était.
+func (g *Game) rollback(before checkpoint) {
+	g.restore(before.snapshot)
+	g.spectatorViews = before.spectatorViews
+	g.bots = nil
+	g.remaining = before.remaining
+	if before.deadline != nil && time.Until(*before.deadline) > 0 {
+		g.startTimer(time.Until(*before.deadline))
+		return
+	}
+	g.scheduleDeadline()
+}
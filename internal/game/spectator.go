@@ -0,0 +1,53 @@
+package game
+
+import "errors"
+
+var ErrAlreadyPlayer = errors.New("players cannot spectate their own game")
+
+// AddSpectator ajoute un spectateur à la partie
+func (g *Game) AddSpectator(name string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, exists := g.Players[name]; exists {
+		return ErrAlreadyPlayer
+	}
+	g.Spectators++
+	return nil
+}
+
+// RemoveSpectator retire un spectateur de la partie
+func (g *Game) RemoveSpectator() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Spectators > 0 {
+		g.Spectators--
+	}
+}
+
+// SpectatorView retourne la vue publique de la partie, en retard de Rules.SpectatorDelayEvents événements
+func (g *Game) SpectatorView() *GameView {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if g.Rules.SpectatorDelayEvents <= 0 || len(g.spectatorViews) == 0 {
+		return g.view("")
+	}
+	view := *g.spectatorViews[0]
+	view.Spectators = g.Spectators
+	return &view
+}
+
+// recordSpectatorView garde la vue publique des derniers événements pour le différé, le verrou doit être tenu
+func (g *Game) recordSpectatorView() {
+	delay := g.Rules.SpectatorDelayEvents
+	if delay <= 0 {
+		g.spectatorViews = nil
+		return
+	}
+	g.spectatorViews = append(g.spectatorViews, g.view(""))
+	if len(g.spectatorViews) > delay+1 {
+		g.spectatorViews = g.spectatorViews[len(g.spectatorViews)-delay-1:]
+	}
+}
@@ -0,0 +1,47 @@
+package game
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSpectatorViewIsDelayedAndHidesHands(t *testing.T) {
+	const delay = 3
+	g := NewGame("p1")
+	g.Rules = Ruleset{SpectatorDelayEvents: delay}
+	g.Seed = 1
+	for i := 1; i <= 4; i++ {
+		g.AddPlayer(NewPlayer(fmt.Sprintf("p%d", i), 0))
+	}
+	if err := g.StartGame(); err != nil {
+		t.Fatalf("StartGame: %v", err)
+	}
+	if err := g.AddSpectator("watcher"); err != nil {
+		t.Fatalf("AddSpectator: %v", err)
+	}
+	if err := g.AddSpectator("p1"); err != ErrAlreadyPlayer {
+		t.Fatalf("a player spectates their own game: got %v, want %v", err, ErrAlreadyPlayer)
+	}
+
+	for step := 0; step < 40 && g.GetState() == GameStateStarted; step++ {
+		view := g.SpectatorView()
+		if want := g.LastSeq() - delay; view.Seq != want {
+			t.Fatalf("spectators see seq %d at seq %d, want %d", view.Seq, g.LastSeq(), want)
+		}
+		if view.Hand != nil || view.Viewer != "" || view.Spectators != 1 {
+			t.Fatalf("spectator view is not the public view: %+v", view)
+		}
+		for name, player := range view.Players {
+			if player.Role != "" && player.Role != RoleShogun {
+				t.Fatalf("spectators see %s's role", name)
+			}
+		}
+
+		g.mu.Lock()
+		cmd := g.botCommand(g.Players[g.waitingOn()])
+		g.mu.Unlock()
+		if err := g.Apply(cmd); err != nil {
+			t.Fatalf("apply %+v: %v", cmd, err)
+		}
+	}
+}
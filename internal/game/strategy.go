@@ -0,0 +1,606 @@
+package game
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Niveaux de l'IA stratégique
+const (
+	BotEasy   = "easy"
+	BotNormal = "normal"
+	BotHard   = "hard"
+)
+
+// Paramètres de la recherche Monte Carlo du niveau difficile
+const (
+	hardCandidates   = 6
+	hardSamples      = 16
+	hardRolloutSteps = 30
+)
+
+// safePlayScore est l'intérêt à partir duquel un coup se joue sans réfléchir :
+// propriétés, pioche et soin
+const safePlayScore = 60
+
+// deckCards est le paquet de base, indexé par identifiant de carte moins un
+var deckCards = newDeck()
+
+// cardByID retourne une carte du paquet de base par son identifiant
+func cardByID(id int) (Card, bool) {
+	if id < 1 || id > len(deckCards) {
+		return Card{}, false
+	}
+	return deckCards[id-1], true
+}
+
+// StrategicBot raisonne sur les rôles cachés à partir de sa vue et des
+// événements publics : il retient qui attaque qui pour estimer les équipes,
+// épargne et soigne le Shogun s'il est Samouraï et joue pour le score du Ronin.
+// Le niveau facile joue au hasard parmi les coups utiles sans suivre les
+// attaques, le niveau normal choisit le meilleur coup estimé et le niveau
+// difficile départage les meilleurs coups par échantillonnage Monte Carlo
+// des informations cachées.
+type StrategicBot struct {
+	level   string
+	attacks map[string]map[string]int
+	meddles map[string]map[string]int
+}
+
+// NewStrategicBot crée une IA stratégique du niveau donné
+func NewStrategicBot(level string) *StrategicBot {
+	return &StrategicBot{
+		level:   level,
+		attacks: make(map[string]map[string]int),
+		meddles: make(map[string]map[string]int),
+	}
+}
+
+// Observe retient les attaques et les cartes jouées contre chaque joueur
+func (b *StrategicBot) Observe(event Event) {
+	if event.Type != EventCommand && event.Type != EventAutoCommand {
+		return
+	}
+	// Les événements relus depuis un snapshot ont perdu leur type
+	cmd, ok := event.Data.(Command)
+	if !ok {
+		data, err := json.Marshal(event.Data)
+		if err != nil || json.Unmarshal(data, &cmd) != nil {
+			return
+		}
+	}
+	if cmd.Type != CommandPlay || cmd.Target == "" {
+		return
+	}
+	card, ok := cardByID(cmd.Card)
+	if !ok {
+		return
+	}
+	switch {
+	case card.IsWeapon():
+		countAgainst(b.attacks, cmd.Player, cmd.Target)
+	case card.Name == CardGeisha || card.Name == CardDiversion:
+		countAgainst(b.meddles, cmd.Player, cmd.Target)
+	}
+}
+
+func countAgainst(counts map[string]map[string]int, from, to string) {
+	if counts[from] == nil {
+		counts[from] = make(map[string]int)
+	}
+	counts[from][to]++
+}
+
+// Decide choisit une action à partir de la vue du bot
+func (b *StrategicBot) Decide(view *GameView) Command {
+	return b.Plan(view)()
+}
+
+// Plan estime les rôles et les coups utiles, puis retourne le coup choisi ou,
+// au niveau difficile, la recherche Monte Carlo qui le départage
+func (b *StrategicBot) Plan(view *GameView) func() Command {
+	rng := rand.New(rand.NewSource(decisionSeed(view)))
+	me := view.Players[view.Viewer]
+	decided := func(cmd Command) func() Command {
+		return func() Command { return cmd }
+	}
+	if len(view.Reactions) > 0 {
+		return decided(b.respond(view, me, rng))
+	}
+	if view.Turn == nil || view.Turn.Phase == PhaseDiscard {
+		return decided(Command{Type: CommandDiscard, Cards: leastUseful(view.Hand, len(view.Hand)-HandLimit)})
+	}
+
+	belief := b.estimateRoles(view)
+	candidates := b.candidates(view, me, belief)
+	endTurn := Command{Type: CommandEndTurn}
+	if len(candidates) == 0 {
+		return decided(endTurn)
+	}
+	switch b.level {
+	case BotEasy:
+		if rng.Float64() < 0.25 {
+			return decided(endTurn)
+		}
+		return decided(candidates[rng.Intn(len(candidates))].cmd)
+	case BotHard:
+		// Les cartes sans cible se jouent d'abord, la recherche ne départage que les coups contre les autres
+		if candidates[0].score < safePlayScore && len(candidates) > 1 {
+			return func() Command { return search(view, belief, candidates, rng) }
+		}
+	}
+	return decided(candidates[0].cmd)
+}
+
+// decisionSeed dérive le hasard d'une décision de la vue, pour qu'une partie
+// rejouée avec la même graine prenne les mêmes décisions
+func decisionSeed(view *GameView) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(view.ID + "/" + view.Viewer))
+	return int64(h.Sum64()) ^ view.Seq
+}
+
+// respond répond à la réaction attendue : parer dès que possible, avec une
+// arme pour Hanzõ, sauf au niveau facile qui oublie parfois de parer
+func (b *StrategicBot) respond(view *GameView, me *PlayerView, rng *rand.Rand) Command {
+	reaction := view.Reactions[0]
+	if b.level == BotEasy && rng.Float64() < 0.3 {
+		return Command{Type: CommandRespond}
+	}
+	answer := answerFor(reaction, view.Hand)
+	if answer == 0 && reaction.Kind != ReactionJujitsu && isCharacter(me, CharacterHanzo) && len(view.Hand) > 1 {
+		answer = answerFor(&Reaction{Kind: ReactionJujitsu}, view.Hand)
+	}
+	return Command{Type: CommandRespond, Card: answer}
+}
+
+// roleBelief estime le rôle de chaque joueur : ceux de la vue sont connus, les
+// autres se partagent les rôles restants
+type roleBelief struct {
+	me        Role
+	odds      map[string]map[Role]float64
+	unknown   []string
+	remaining []Role
+}
+
+// estimateRoles répartit les rôles restants entre les joueurs au rôle caché.
+// Attaquer le Shogun trahit un Ninja ou un Ronin ; attaquer ses adversaires
+// présumés trahit un Samouraï. Le niveau facile ne tient pas compte des attaques.
+func (b *StrategicBot) estimateRoles(view *GameView) *roleBelief {
+	belief := &roleBelief{
+		me:   view.Players[view.Viewer].Role,
+		odds: make(map[string]map[Role]float64, len(view.Players)),
+	}
+	belief.remaining = append(belief.remaining, rolesByPlayerCount[len(view.Players)]...)
+	shogun := ""
+	for _, player := range viewSeating(view) {
+		if player.Role == "" {
+			belief.unknown = append(belief.unknown, player.Name)
+			continue
+		}
+		belief.odds[player.Name] = map[Role]float64{player.Role: 1}
+		belief.remaining = removeRole(belief.remaining, player.Role)
+		if player.Role == RoleShogun {
+			shogun = player.Name
+		}
+	}
+	if len(belief.unknown) == 0 {
+		return belief
+	}
+
+	// Un score négatif désigne un adversaire du Shogun, positif un Samouraï
+	suspicion := make(map[string]float64, len(belief.unknown))
+	if b.level != BotEasy {
+		for _, name := range belief.unknown {
+			suspicion[name] = -3*float64(b.attacks[name][shogun]) - float64(b.meddles[name][shogun])
+		}
+		for _, name := range belief.unknown {
+			for target, count := range b.attacks[name] {
+				if target == shogun {
+					continue
+				}
+				switch {
+				case suspicion[target] < 0:
+					suspicion[name] += float64(count)
+				case suspicion[target] > 0:
+					suspicion[name] -= float64(count)
+				}
+			}
+		}
+	}
+
+	counts := make(map[Role]float64)
+	for _, role := range belief.remaining {
+		counts[role]++
+	}
+	prior := counts[RoleSamurai] / float64(len(belief.unknown))
+	for _, name := range belief.unknown {
+		samurai := prior
+		if prior > 0 && prior < 1 {
+			weight := prior * math.Exp(suspicion[name]/2)
+			samurai = weight / (weight + 1 - prior)
+		}
+		odds := map[Role]float64{RoleSamurai: samurai}
+		if others := counts[RoleNinja] + counts[RoleRonin]; others > 0 {
+			odds[RoleNinja] = (1 - samurai) * counts[RoleNinja] / others
+			odds[RoleRonin] = (1 - samurai) * counts[RoleRonin] / others
+		}
+		belief.odds[name] = odds
+	}
+	return belief
+}
+
+func removeRole(roles []Role, role Role) []Role {
+	for i, r := range roles {
+		if r == role {
+			return append(roles[:i:i], roles[i+1:]...)
+		}
+	}
+	return roles
+}
+
+// hostility mesure l'intérêt d'affaiblir un joueur, négatif pour un allié probable
+func (belief *roleBelief) hostility(name string) float64 {
+	// Sommer dans un ordre fixe pour qu'une même graine rejoue la même partie
+	total := 0.0
+	for _, role := range []Role{RoleShogun, RoleSamurai, RoleNinja, RoleRonin} {
+		total += belief.odds[name][role] * enemyValue(belief.me, role)
+	}
+	return total
+}
+
+// enemyValue donne l'intérêt pour un rôle d'affaiblir un joueur d'un autre rôle
+func enemyValue(me, other Role) float64 {
+	switch me {
+	case RoleShogun, RoleSamurai:
+		switch other {
+		case RoleShogun:
+			return -2
+		case RoleSamurai:
+			return -1
+		}
+		return 1
+	case RoleNinja:
+		switch other {
+		case RoleShogun:
+			return 2
+		case RoleSamurai:
+			return 1
+		case RoleNinja:
+			return -1
+		}
+		return 0.5
+	}
+	// Le Ronin gagne seul : tous les autres sont des adversaires, le Shogun le premier
+	if other == RoleShogun {
+		return 1.5
+	}
+	return 1
+}
+
+// sample tire une répartition des rôles cachés, les Samouraïs selon leur
+// probabilité estimée et les autres rôles au hasard
+func (belief *roleBelief) sample(rng *rand.Rand) map[string]Role {
+	roles := make(map[string]Role, len(belief.odds))
+	for name, odds := range belief.odds {
+		if len(odds) == 1 {
+			for role := range odds {
+				roles[name] = role
+			}
+		}
+	}
+
+	unknown := append([]string(nil), belief.unknown...)
+	others := make([]Role, 0, len(belief.remaining))
+	for _, role := range belief.remaining {
+		if role != RoleSamurai || len(unknown) == 0 {
+			others = append(others, role)
+			continue
+		}
+		// Tirage pondéré sans remise d'un Samouraï
+		total := 0.0
+		for _, name := range unknown {
+			total += belief.odds[name][RoleSamurai] + 1e-6
+		}
+		pick := rng.Float64() * total
+		index := len(unknown) - 1
+		for i, name := range unknown {
+			pick -= belief.odds[name][RoleSamurai] + 1e-6
+			if pick <= 0 {
+				index = i
+				break
+			}
+		}
+		roles[unknown[index]] = RoleSamurai
+		unknown = append(unknown[:index:index], unknown[index+1:]...)
+	}
+	rng.Shuffle(len(others), func(i, j int) { others[i], others[j] = others[j], others[i] })
+	for i, name := range unknown {
+		if i < len(others) {
+			roles[name] = others[i]
+		}
+	}
+	return roles
+}
+
+// candidate est un coup envisagé et son intérêt estimé
+type candidate struct {
+	cmd   Command
+	score float64
+}
+
+// candidates retourne les coups utiles de la phase de jeu, du plus intéressant au moins intéressant
+func (b *StrategicBot) candidates(view *GameView, me *PlayerView, belief *roleBelief) []candidate {
+	seats := viewSeating(view)
+	var candidates []candidate
+	add := func(cmd Command, score float64) {
+		cmd.Type = CommandPlay
+		candidates = append(candidates, candidate{cmd: cmd, score: score})
+	}
+
+	for _, card := range view.Hand {
+		cmd := Command{Card: card.ID}
+		switch {
+		case card.Kind == CardKindProperty:
+			add(cmd, 90)
+
+		case card.Name == CardDaimyo:
+			add(cmd, 80)
+
+		case card.Name == CardTeaCeremony:
+			add(cmd, 70)
+
+		case card.Name == CardMeditation:
+			if me.Character == nil || me.Life >= me.Character.Life {
+				continue
+			}
+			// La carte offerte va à l'allié le plus probable, le Shogun pour un Samouraï
+			ally, best := "", -0.5
+			for _, other := range seats {
+				if other != me {
+					if h := belief.hostility(other.Name); h < best {
+						ally, best = other.Name, h
+					}
+				}
+			}
+			cmd.Target = ally
+			add(cmd, 60)
+
+		case card.IsWeapon():
+			if view.Turn.WeaponsPlayed >= viewWeaponLimit(me) {
+				continue
+			}
+			for _, target := range seats {
+				h := belief.hostility(target.Name)
+				if target == me || target.Life <= 0 || h <= 0 {
+					continue
+				}
+				if !isCharacter(me, CharacterKojiro) && card.Range < viewDistance(seats, me, target) {
+					continue
+				}
+				damage := viewWeaponDamage(me, target, card)
+				score := 20 + 15*h + 3*float64(damage) - float64(target.Life)
+				// Rendre un joueur inoffensif rapporte un point d'honneur, ce qui compte double pour le Ronin
+				if damage >= target.Life {
+					score += 15
+					if belief.me == RoleRonin {
+						score += 10
+					}
+				}
+				cmd.Target = target.Name
+				add(cmd, score)
+			}
+
+		case card.Name == CardBattlecry || card.Name == CardJujitsu:
+			total := 0.0
+			for _, other := range seats {
+				if other != me && other.Life > 0 && !isCharacter(other, CharacterChiyome) {
+					total += belief.hostility(other.Name)
+				}
+			}
+			if total > 0.5 {
+				add(cmd, 30+5*total)
+			}
+
+		case card.Name == CardGeisha:
+			for _, target := range seats {
+				h := belief.hostility(target.Name)
+				if target == me || h <= 0 {
+					continue
+				}
+				cmd.Target = target.Name
+				cmd.TargetCard = 0
+				switch {
+				case len(target.InPlay) > 0:
+					cmd.TargetCard = target.InPlay[0].ID
+					add(cmd, 25+10*h)
+				case target.HandSize > 0:
+					add(cmd, 15+5*h)
+				}
+			}
+
+		case card.Name == CardDiversion:
+			for _, target := range seats {
+				h := belief.hostility(target.Name)
+				if target != me && h > 0 && target.HandSize > 0 {
+					cmd.Target = target.Name
+					add(cmd, 20+5*h+float64(target.HandSize))
+				}
+			}
+		}
+	}
+
+	// Nobunaga pioche contre 1 point de vie tant qu'il lui en reste une marge
+	if isCharacter(me, CharacterNobunaga) && me.Life > 2 {
+		candidates = append(candidates, candidate{cmd: Command{Type: CommandAbility}, score: 10 + 5*float64(me.Life)})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+	return candidates
+}
+
+// search vérifie le meilleur coup estimé sur des parties échantillonnées : les
+// rôles et les cartes cachés sont tirés selon les rôles estimés, puis chaque
+// partie est poursuivie par des bots et évaluée pour l'équipe du bot. Un autre
+// coup n'est préféré que si son avance sur les mêmes parties tirées dépasse
+// nettement le bruit de l'échantillonnage.
+func search(view *GameView, belief *roleBelief, candidates []candidate, rng *rand.Rand) Command {
+	if len(candidates) > hardCandidates {
+		candidates = candidates[:hardCandidates]
+	}
+
+	values := make([][]float64, len(candidates))
+	for sample := 0; sample < hardSamples; sample++ {
+		roles := belief.sample(rng)
+		seed := rng.Int63()
+		// Tous les coups sont évalués sur la même partie tirée
+		for i, c := range candidates {
+			g := determinize(view, roles, rand.New(rand.NewSource(seed)))
+			values[i] = append(values[i], rollout(g, view.Viewer, c.cmd))
+		}
+	}
+
+	best, bestGain := 0, 0.0
+	for i := 1; i < len(candidates); i++ {
+		mean, variance := 0.0, 0.0
+		for sample := range values[i] {
+			mean += values[i][sample] - values[0][sample]
+		}
+		mean /= hardSamples
+		for sample := range values[i] {
+			d := values[i][sample] - values[0][sample] - mean
+			variance += d * d
+		}
+		stderr := math.Sqrt(variance / (hardSamples - 1) / hardSamples)
+		if mean > 2*stderr && mean > bestGain {
+			best, bestGain = i, mean
+		}
+	}
+	return candidates[best].cmd
+}
+
+// determinize construit une partie complète compatible avec une vue : les
+// cartes que le bot ne voit pas sont distribuées au hasard entre les mains
+// adverses, le paquet et la défausse
+func determinize(view *GameView, roles map[string]Role, rng *rand.Rand) *Game {
+	known := make(map[int]bool)
+	for _, card := range view.Hand {
+		known[card.ID] = true
+	}
+	for _, player := range view.Players {
+		for _, card := range player.InPlay {
+			known[card.ID] = true
+		}
+	}
+	if view.DiscardTop != nil {
+		known[view.DiscardTop.ID] = true
+	}
+	pool := make([]Card, 0, len(deckCards))
+	for _, card := range deckCards {
+		if !known[card.ID] {
+			pool = append(pool, card)
+		}
+	}
+	rng.Shuffle(len(pool), func(i, j int) { pool[i], pool[j] = pool[j], pool[i] })
+	take := func(count int) []Card {
+		if count > len(pool) {
+			count = len(pool)
+		}
+		cards := append([]Card(nil), pool[:count]...)
+		pool = pool[count:]
+		return cards
+	}
+
+	g := &Game{
+		ID:          view.ID,
+		State:       GameStateStarted,
+		Players:     make(map[string]*Player, len(view.Players)),
+		MaxPlayers:  view.MaxPlayers,
+		Exhaustions: view.Exhaustions,
+		Seed:        rng.Int63(),
+		// La partie tirée est poursuivie par rollout, sans délai armé pour ses bots
+		manualBots: true,
+	}
+	if view.Turn != nil {
+		turn := *view.Turn
+		g.Turn = &turn
+	}
+	for _, reaction := range view.Reactions {
+		r := *reaction
+		g.Reactions = append(g.Reactions, &r)
+	}
+	for _, seat := range viewSeating(view) {
+		player := &Player{
+			Name:      seat.Name,
+			Position:  seat.Position,
+			Life:      seat.Life,
+			Honor:     seat.Honor,
+			Character: seat.Character,
+			InPlay:    append([]Card(nil), seat.InPlay...),
+			Bot:       true,
+			BotKind:   BotHeuristic,
+			Role:      roles[seat.Name],
+		}
+		if seat.Name == view.Viewer {
+			player.Hand = append([]Card(nil), view.Hand...)
+		} else {
+			player.Hand = take(seat.HandSize)
+		}
+		g.Players[seat.Name] = player
+	}
+	g.Deck = take(view.DeckSize)
+	g.Discard = take(len(pool))
+	if view.DiscardTop != nil {
+		g.Discard = append(g.Discard, *view.DiscardTop)
+	}
+	return g
+}
+
+// rollout joue un coup sur une partie tirée, la poursuit avec des bots
+// heuristiques puis l'évalue pour l'équipe du bot
+func rollout(g *Game, me string, cmd Command) float64 {
+	cmd.Player = me
+	if err := g.apply(cmd, true); err != nil {
+		return -1000
+	}
+	for step := 0; step < hardRolloutSteps && g.State == GameStateStarted; step++ {
+		player := g.Players[g.waitingOn()]
+		if g.apply(g.botCommand(player), true) != nil && g.apply(g.defaultCommand(player), true) != nil {
+			break
+		}
+	}
+	return evaluate(g, me)
+}
+
+// evaluate mesure l'avance de l'équipe d'un joueur : les points d'honneur
+// pondérés et de vie de ses alliés moins ceux de ses adversaires, et une
+// prime à la victoire
+func evaluate(g *Game, me string) float64 {
+	myTeam := TeamOf(g.Players[me].Role)
+	value := 0.0
+	for _, player := range g.Players {
+		team := TeamOf(player.Role)
+		score := float64(player.Honor*teamMultiplier(team, len(g.Players))) + 0.5*float64(player.Life)
+		if team == myTeam {
+			value += score
+		} else {
+			value -= score
+		}
+	}
+	if g.Result != nil {
+		if g.Result.Winner == myTeam {
+			value += 10
+		} else {
+			value -= 10
+		}
+	}
+	return value
+}
+
+// viewWeaponDamage calcule depuis une vue les dégâts d'une arme, comme weaponDamage
+func viewWeaponDamage(attacker, target *PlayerView, weapon Card) int {
+	return damageOf(weapon, attacker.InPlay, attacker.Character, target.Character)
+}
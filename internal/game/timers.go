@@ -0,0 +1,227 @@
+package game
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// Événements liés aux délais de jeu
+const (
+	EventTimeout   = "timeout"
+	EventPlayerAFK = "player_afk"
+	EventSeatToBot = "seat_to_bot"
+)
+
+// Ruleset regroupe les délais de jeu d'une partie, un délai à 0 le désactive,
+// sauf BotDelaySeconds : à 0, un siège joué par le serveur agit sans attendre
+type Ruleset struct {
+	PlayTimeoutSeconds     int `json:"play_timeout_seconds"`
+	DiscardTimeoutSeconds  int `json:"discard_timeout_seconds"`
+	ReactionTimeoutSeconds int `json:"reaction_timeout_seconds"`
+	BotDelaySeconds        int `json:"bot_delay_seconds"`
+	AFKAfterTimeouts       int `json:"afk_after_timeouts"`
+	BotAfterTimeouts       int `json:"bot_after_timeouts"`
+	SpectatorDelayEvents   int `json:"spectator_delay_events"`
+}
+
+// DefaultRuleset retourne les délais utilisés par défaut
+func DefaultRuleset() Ruleset {
+	return Ruleset{
+		PlayTimeoutSeconds:     90,
+		DiscardTimeoutSeconds:  30,
+		ReactionTimeoutSeconds: 20,
+		BotDelaySeconds:        1,
+		AFKAfterTimeouts:       2,
+		BotAfterTimeouts:       4,
+	}
+}
+
+// autoplayed indique si le siège est joué par le serveur
+func (p *Player) autoplayed() bool {
+	return p.Bot || p.Abandoned
+}
+
+// timeout retourne le délai accordé au joueur attendu
+func (g *Game) timeout() time.Duration {
+	player := g.Players[g.waitingOn()]
+	seconds := g.Rules.PlayTimeoutSeconds
+	switch {
+	case player != nil && player.autoplayed():
+		seconds = g.Rules.BotDelaySeconds
+	case len(g.Reactions) > 0:
+		seconds = g.Rules.ReactionTimeoutSeconds
+	case g.Turn != nil && g.Turn.Phase == PhaseDiscard:
+		seconds = g.Rules.DiscardTimeoutSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// scheduleDeadline arme le délai de la prochaine action attendue, le verrou doit être tenu
+func (g *Game) scheduleDeadline() {
+	player := g.Players[g.waitingOn()]
+	if player == nil || !player.autoplayed() {
+		g.startTimer(g.timeout())
+		return
+	}
+
+	// Un siège joué par le serveur agit toujours, même sans délai, pour que
+	// la table ne reste pas bloquée sur lui
+	g.stopTimer()
+	if g.State == GameStateStarted && !g.manualBots {
+		g.armTimer(g.timeout())
+	}
+}
+
+// freezeDeadline arrête le délai en cours et garde le temps restant pour la reprise
+func (g *Game) freezeDeadline() {
+	g.remaining = 0
+	if g.Deadline != nil {
+		g.remaining = time.Until(*g.Deadline)
+	}
+	g.stopTimer()
+}
+
+// thawDeadline réarme le délai figé par freezeDeadline
+func (g *Game) thawDeadline() {
+	if g.remaining <= 0 {
+		g.scheduleDeadline()
+		return
+	}
+	g.startTimer(g.remaining)
+	g.remaining = 0
+}
+
+func (g *Game) startTimer(d time.Duration) {
+	g.stopTimer()
+	if g.State != GameStateStarted || d <= 0 {
+		return
+	}
+	g.armTimer(d)
+}
+
+// armTimer lance le délai d, le précédent doit être arrêté
+func (g *Game) armTimer(d time.Duration) {
+	deadline := time.Now().Add(d)
+	g.Deadline = &deadline
+	generation := g.timerGeneration
+	g.timer = time.AfterFunc(d, func() { g.expire(generation) })
+}
+
+func (g *Game) stopTimer() {
+	if g.timer != nil {
+		g.timer.Stop()
+		g.timer = nil
+	}
+	g.timerGeneration++
+	g.Deadline = nil
+}
+
+// expire joue l'action par défaut du joueur attendu quand son délai est dépassé.
+// Un bot décide hors du verrou, sur sa vue copiée, et son coup n'est joué que
+// si la partie n'a pas bougé entre-temps
+func (g *Game) expire(generation int) {
+	g.mu.Lock()
+	if generation != g.timerGeneration || g.State != GameStateStarted {
+		g.mu.Unlock()
+		return
+	}
+	var planned *Command
+	if player := g.Players[g.waitingOn()]; player.Bot {
+		seq := g.Seq
+		decide := g.botPlan(player)
+		g.mu.Unlock()
+		cmd := decide()
+		cmd.Player = player.Name
+		g.mu.Lock()
+		if generation != g.timerGeneration || g.State != GameStateStarted {
+			g.mu.Unlock()
+			return
+		}
+		if seq != g.Seq {
+			// Un événement a changé la vue sans réarmer le délai : le bot décidera à nouveau
+			g.scheduleDeadline()
+			g.mu.Unlock()
+			return
+		}
+		planned = &cmd
+	}
+	var event string
+	err := g.change(func() (err error) {
+		event, err = g.autoplay(planned)
+		return err
+	})
+	g.mu.Unlock()
+	// Une action annulée faute de journal est retentée au délai réarmé par l'annulation
+	if err != nil {
+		return
+	}
+
+	GetGameManager().notifyTimeout(g, event)
+}
+
+// autoplay applique l'action par défaut et compte les délais dépassés par un
+// humain ; planned est le coup déjà choisi par le bot du siège, s'il y en a un
+func (g *Game) autoplay(planned *Command) (string, error) {
+	player := g.Players[g.waitingOn()]
+	event := EventAutoCommand
+	if !player.autoplayed() {
+		event = EventTimeout
+		player.Timeouts++
+		if err := g.record(EventTimeout, player.Name, nil); err != nil {
+			return event, err
+		}
+
+		var err error
+		if g.Rules.BotAfterTimeouts > 0 && player.Timeouts >= g.Rules.BotAfterTimeouts {
+			player.Bot = true
+			event = EventSeatToBot
+			err = g.record(EventSeatToBot, player.Name, nil)
+		} else if g.Rules.AFKAfterTimeouts > 0 && player.Timeouts >= g.Rules.AFKAfterTimeouts && !player.AFK {
+			player.AFK = true
+			event = EventPlayerAFK
+			err = g.record(EventPlayerAFK, player.Name, nil)
+		}
+		if err != nil {
+			return event, err
+		}
+	}
+
+	// Un siège de bot joue l'action de son bot si elle est légale
+	if player.Bot {
+		if planned == nil {
+			cmd := g.botCommand(player)
+			planned = &cmd
+		}
+		if err := g.apply(*planned, true); err == nil || errors.Is(err, ErrLogWrite) {
+			return event, err
+		}
+	}
+	if err := g.apply(g.defaultCommand(player), true); err != nil {
+		if errors.Is(err, ErrLogWrite) {
+			return event, err
+		}
+		// L'action par défaut est toujours légale, sinon on réarme pour ne pas bloquer la table
+		g.scheduleDeadline()
+	}
+	return event, nil
+}
+
+// defaultCommand retourne l'action sûre du joueur attendu : subir au lieu de parer,
+// défausser au hasard jusqu'à la limite de main, ou terminer la phase de jeu
+func (g *Game) defaultCommand(player *Player) Command {
+	if len(g.Reactions) > 0 {
+		return Command{Type: CommandRespond, Player: player.Name}
+	}
+	if g.Turn.Phase != PhaseDiscard {
+		return Command{Type: CommandEndTurn, Player: player.Name}
+	}
+
+	rng := rand.New(rand.NewSource(g.Seed + int64(g.Commands)))
+	excess := len(player.Hand) - HandLimit
+	cards := make([]int, 0, excess)
+	for _, index := range rng.Perm(len(player.Hand))[:excess] {
+		cards = append(cards, player.Hand[index].ID)
+	}
+	return Command{Type: CommandDiscard, Player: player.Name, Cards: cards}
+}
@@ -0,0 +1,96 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBotWithoutDelayActsImmediately(t *testing.T) {
+	g := NewGame("test")
+	g.Rules = Ruleset{}
+	g.Seed = 1
+	for _, kind := range []string{BotNormal, BotHard, BotHeuristic} {
+		if _, err := g.AddBot(kind); err != nil {
+			t.Fatalf("AddBot: %v", err)
+		}
+	}
+	if err := g.StartGame(); err != nil {
+		t.Fatalf("bot game did not start: %v", err)
+	}
+
+	// Sans délai, les bots enchaînent leurs actions jusqu'à la fin de la partie,
+	// et la recherche du bot difficile laisse lire la partie pendant ce temps
+	deadline := time.Now().Add(30 * time.Second)
+	for g.GetState() == GameStateStarted {
+		g.View("")
+		if time.Now().After(deadline) {
+			g.mu.RLock()
+			defer g.mu.RUnlock()
+			t.Fatalf("bot table stalled after %d commands", g.Commands)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// expireUntil fait dépasser leur délai aux joueurs attendus jusqu'à ce que done soit vrai
+func expireUntil(t *testing.T, g *Game, what string, done func() bool) {
+	t.Helper()
+	for step := 0; !done(); step++ {
+		if step == 200 || g.GetState() != GameStateStarted {
+			t.Fatalf("game over before %s", what)
+		}
+		g.mu.RLock()
+		generation := g.timerGeneration
+		g.mu.RUnlock()
+		g.expire(generation)
+	}
+}
+
+func TestTimeoutsHandSeatToBotUntilPlayerReturns(t *testing.T) {
+	g, log := loggedGame(t, 4)
+	g.Rules.AFKAfterTimeouts = 1
+	g.Rules.BotAfterTimeouts = 2
+	target := g.Players["p1"]
+	other := g.Players["p2"]
+
+	expireUntil(t, g, "p1's first timeout", func() bool { return target.Timeouts == 1 })
+	if !target.AFK || target.Bot {
+		t.Fatalf("after one timeout p1 is afk=%v bot=%v, want afk only", target.AFK, target.Bot)
+	}
+	expireUntil(t, g, "p1 and p2 are played by bots", func() bool { return target.Bot && other.Bot })
+
+	// Le joueur qui agit de lui-même reprend son siège
+	expireUntil(t, g, "p1's turn", func() bool { return g.waitingOn() == "p1" && len(g.Reactions) == 0 })
+	g.mu.Lock()
+	cmd := g.defaultCommand(target)
+	g.mu.Unlock()
+	if err := g.Apply(cmd); err != nil {
+		t.Fatalf("p1 plays: %v", err)
+	}
+	if target.Bot || target.AFK || target.Timeouts != 0 || g.bots["p1"] != nil {
+		t.Fatalf("p1 acted but the server still plays their seat: %+v", *target)
+	}
+
+	// Le joueur qui revient après une déconnexion aussi
+	if !g.MarkDisconnected("p2") || !g.Reconnect("p2") {
+		t.Fatal("p2 could not reconnect")
+	}
+	if other.Bot || other.AFK || other.Timeouts != 0 {
+		t.Fatalf("p2 came back but the server still plays their seat: %+v", *other)
+	}
+
+	// La relecture du journal rend les mêmes sièges
+	entries, err := log.Load(g.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := ReplayGame(entries)
+	if err != nil {
+		t.Fatalf("ReplayGame: %v", err)
+	}
+	for _, name := range []string{"p1", "p2", "p3", "p4"} {
+		if got, want := *replayed.Players[name], *g.Players[name]; got.Bot != want.Bot || got.AFK != want.AFK || got.Timeouts != want.Timeouts {
+			t.Fatalf("replayed %s is %+v, want %+v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package game
+
+import "time"
+
+// PlayerView représente un joueur tel qu'un autre joueur le voit
+type PlayerView struct {
+	Name           string     `json:"name"`
+	Position       int        `json:"position"`
+	Life           int        `json:"life"`
+	Honor          int        `json:"honor"`
+	Character      *Character `json:"character,omitempty"`
+	InPlay         []Card     `json:"in_play,omitempty"`
+	HandSize       int        `json:"hand_size"`
+	Role           Role       `json:"role,omitempty"`
+	DisconnectedAt *time.Time `json:"disconnected_at,omitempty"`
+	Abandoned      bool       `json:"abandoned,omitempty"`
+	Kicked         bool       `json:"kicked,omitempty"`
+	AFK            bool       `json:"afk,omitempty"`
+	Bot            bool       `json:"bot,omitempty"`
+	BotKind        string     `json:"bot_kind,omitempty"`
+}
+
+// GameView représente la projection d'une partie pour un spectateur donné
+type GameView struct {
+	ID          string                 `json:"id"`
+	State       GameState              `json:"state"`
+	Seq         int64                  `json:"seq"`
+	CreatedBy   string                 `json:"created_by"`
+	CreatedAt   time.Time              `json:"created_at"`
+	MaxPlayers  int                    `json:"max_players"`
+	Players     map[string]*PlayerView `json:"players"`
+	Turn        *Turn                  `json:"turn,omitempty"`
+	Reactions   []*Reaction            `json:"reactions,omitempty"`
+	DeckSize    int                    `json:"deck_size"`
+	DiscardTop  *Card                  `json:"discard_top,omitempty"`
+	Exhaustions int                    `json:"exhaustions"`
+	Result      *Result                `json:"result,omitempty"`
+	PausedAt    *time.Time             `json:"paused_at,omitempty"`
+	PauseReason PauseReason            `json:"pause_reason,omitempty"`
+	Deadline    *time.Time             `json:"deadline,omitempty"`
+	Spectators  int                    `json:"spectators"`
+	Viewer      string                 `json:"viewer,omitempty"`
+	Hand        []Card                 `json:"hand,omitempty"`
+}
+
+// View retourne ce que viewer a le droit de voir : sa main et son rôle, le Shogun, et tous les rôles en fin de partie
+func (g *Game) View(viewer string) *GameView {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.view(viewer)
+}
+
+// view construit la projection d'une partie, le verrou doit être tenu
+func (g *Game) view(viewer string) *GameView {
+	view := &GameView{
+		ID:          g.ID,
+		State:       g.State,
+		Seq:         g.Seq,
+		CreatedBy:   g.CreatedBy,
+		CreatedAt:   g.CreatedAt,
+		MaxPlayers:  g.MaxPlayers,
+		Players:     make(map[string]*PlayerView, len(g.Players)),
+		DeckSize:    len(g.Deck),
+		Exhaustions: g.Exhaustions,
+		Result:      g.Result,
+		PausedAt:    g.PausedAt,
+		PauseReason: g.PauseReason,
+		Deadline:    g.Deadline,
+		Spectators:  g.Spectators,
+	}
+	if g.Turn != nil {
+		turn := *g.Turn
+		view.Turn = &turn
+	}
+	for _, reaction := range g.Reactions {
+		r := *reaction
+		view.Reactions = append(view.Reactions, &r)
+	}
+	if len(g.Discard) > 0 {
+		top := g.Discard[len(g.Discard)-1]
+		view.DiscardTop = &top
+	}
+
+	for name, player := range g.Players {
+		playerView := &PlayerView{
+			Name:           player.Name,
+			Position:       player.Position,
+			Life:           player.Life,
+			Honor:          player.Honor,
+			Character:      player.Character,
+			InPlay:         append([]Card(nil), player.InPlay...),
+			HandSize:       len(player.Hand),
+			DisconnectedAt: player.DisconnectedAt,
+			Abandoned:      player.Abandoned,
+			Kicked:         player.Kicked,
+			AFK:            player.AFK,
+			Bot:            player.Bot,
+			BotKind:        player.BotKind,
+		}
+		if name == viewer || player.Role == RoleShogun || g.State == GameStateEnded {
+			playerView.Role = player.Role
+		}
+		view.Players[name] = playerView
+	}
+
+	if player, exists := g.Players[viewer]; exists {
+		view.Viewer = viewer
+		view.Hand = append([]Card{}, player.Hand...)
+	}
+	return view
+}
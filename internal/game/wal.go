@@ -0,0 +1,273 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+var (
+	ErrLogCorrupted = errors.New("command log does not match the game")
+	ErrLogWrite     = errors.New("game event could not be written to the log")
+)
+
+// CommandLog est un journal des événements de chaque partie, écrit sur disque avant tout acquittement
+type CommandLog interface {
+	Append(gameID string, event Event) error
+	Load(gameID string) ([]LogEntry, error)
+	GameIDs() ([]string, error)
+	Delete(gameID string) error
+}
+
+// LogEntry représente un événement relu depuis le journal, ses données encore encodées
+type LogEntry struct {
+	Seq    int64           `json:"seq"`
+	Type   string          `json:"type"`
+	Player string          `json:"player,omitempty"`
+	Data   json.RawMessage `json:"data,omitempty"`
+	At     time.Time       `json:"at"`
+}
+
+// gameCreated contient de quoi recréer une partie vide lors de la relecture du journal
+type gameCreated struct {
+	ID         string    `json:"id"`
+	CreatedBy  string    `json:"created_by"`
+	CreatedAt  time.Time `json:"created_at"`
+	MaxPlayers int       `json:"max_players"`
+	Seed       int64     `json:"seed"`
+	Rules      Ruleset   `json:"rules"`
+}
+
+// now retourne l'heure du prochain événement : la même pour tout un changement, et celle
+// d'origine pendant une relecture
+func (g *Game) now() time.Time {
+	if at, exists := g.replayTimes[g.Seq+1]; exists {
+		return at
+	}
+	if g.pendingAt.IsZero() {
+		g.pendingAt = time.Now()
+	}
+	return g.pendingAt
+}
+
+// recordCreation ouvre le journal de la partie avec ses paramètres de création
+func (g *Game) recordCreation() error {
+	return g.record(EventGameCreated, g.CreatedBy, gameCreated{
+		ID:         g.ID,
+		CreatedBy:  g.CreatedBy,
+		CreatedAt:  g.CreatedAt,
+		MaxPlayers: g.MaxPlayers,
+		Seed:       g.Seed,
+		Rules:      g.Rules,
+	})
+}
+
+// appendToLog écrit un événement dans le journal avant que l'action ne soit acquittée
+func (g *Game) appendToLog(event Event) error {
+	if g.commandLog == nil {
+		return nil
+	}
+	if err := g.commandLog.Append(g.ID, event); err != nil {
+		log.Printf("Erreur lors de l'écriture du journal de la partie %s: %v", g.ID, err)
+		return fmt.Errorf("%w: %v", ErrLogWrite, err)
+	}
+	return nil
+}
+
+// ReplayGame reconstruit une partie à partir de son journal complet
+func ReplayGame(entries []LogEntry) (*Game, error) {
+	if len(entries) == 0 || entries[0].Type != EventGameCreated {
+		return nil, ErrLogCorrupted
+	}
+
+	var created gameCreated
+	if err := json.Unmarshal(entries[0].Data, &created); err != nil {
+		return nil, err
+	}
+	g := &Game{
+		ID:         created.ID,
+		State:      GameStateWaiting,
+		Players:    make(map[string]*Player),
+		CreatedBy:  created.CreatedBy,
+		CreatedAt:  created.CreatedAt,
+		UpdatedAt:  created.CreatedAt,
+		MaxPlayers: created.MaxPlayers,
+		Seed:       created.Seed,
+		Rules:      created.Rules,
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g, g.replay(entries)
+}
+
+// Replay applique les entrées du journal postérieures au dernier événement connu de la partie
+func (g *Game) Replay(entries []LogEntry) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.replay(entries)
+}
+
+// replay rejoue les événements racines ; les événements qu'ils entraînent sont recréés
+// par le moteur avec le même numéro et sont donc ignorés
+func (g *Game) replay(entries []LogEntry) error {
+	g.replaying = true
+	g.replayTimes = make(map[int64]time.Time, len(entries))
+	for _, entry := range entries {
+		g.replayTimes[entry.Seq] = entry.At
+	}
+	defer func() {
+		g.replaying = false
+		g.replayTimes = nil
+	}()
+
+	for _, entry := range entries {
+		if entry.Seq <= g.Seq {
+			continue
+		}
+		if entry.Seq != g.Seq+1 {
+			return fmt.Errorf("%w: expected event %d, got %d", ErrLogCorrupted, g.Seq+1, entry.Seq)
+		}
+		if err := g.replayEntry(entry); err != nil {
+			return fmt.Errorf("%w: event %d (%s): %v", ErrLogCorrupted, entry.Seq, entry.Type, err)
+		}
+	}
+	return nil
+}
+
+// replayEntry rejoue un événement racine, le verrou doit être tenu
+func (g *Game) replayEntry(entry LogEntry) error {
+	player := g.Players[entry.Player]
+	needsPlayer := func() error {
+		if player == nil {
+			return ErrNotInGame
+		}
+		return nil
+	}
+
+	switch entry.Type {
+	case EventGameCreated:
+		g.recordCreation()
+
+	case EventPlayerJoined:
+		joined := NewPlayer(entry.Player, 0)
+		if len(entry.Data) > 0 {
+			var bot botJoined
+			if err := json.Unmarshal(entry.Data, &bot); err != nil {
+				return err
+			}
+			joined.Bot = true
+			joined.BotKind = bot.Bot
+		}
+		if g.addPlayer(joined) != nil {
+			return ErrLogCorrupted
+		}
+
+	case EventPlayerLeft:
+		if err := needsPlayer(); err != nil {
+			return err
+		}
+		g.removePlayer(entry.Player)
+
+	case EventGameStarted:
+		g.startGame()
+
+	case EventCommand, EventAutoCommand:
+		var cmd Command
+		if err := json.Unmarshal(entry.Data, &cmd); err != nil {
+			return err
+		}
+		if err := g.apply(cmd, entry.Type == EventAutoCommand); err != nil {
+			return err
+		}
+		if entry.Type == EventCommand {
+			g.resetTimeouts(cmd.Player)
+		}
+
+	case EventTimeout:
+		if err := needsPlayer(); err != nil {
+			return err
+		}
+		player.Timeouts++
+		g.record(EventTimeout, player.Name, nil)
+
+	case EventPlayerAFK:
+		if err := needsPlayer(); err != nil {
+			return err
+		}
+		player.AFK = true
+		g.record(EventPlayerAFK, player.Name, nil)
+
+	case EventSeatToBot:
+		if err := needsPlayer(); err != nil {
+			return err
+		}
+		player.Bot = true
+		g.record(EventSeatToBot, player.Name, nil)
+
+	case EventPauseVote:
+		g.vote(entry.Player)
+		g.record(EventPauseVote, entry.Player, nil)
+
+	case EventGamePaused:
+		var reason PauseReason
+		if err := json.Unmarshal(entry.Data, &reason); err != nil {
+			return err
+		}
+		g.pause(reason)
+
+	case EventGameResumed:
+		g.resume()
+
+	case EventPlayerDisconnected:
+		if err := needsPlayer(); err != nil {
+			return err
+		}
+		now := g.now()
+		player.DisconnectedAt = &now
+		g.record(EventPlayerDisconnected, player.Name, nil)
+
+	case EventPlayerReconnected:
+		if err := needsPlayer(); err != nil {
+			return err
+		}
+		player.DisconnectedAt = nil
+		player.Abandoned = false
+		g.record(EventPlayerReconnected, player.Name, nil)
+		g.resetTimeouts(player.Name)
+
+	case EventSeatReleased:
+		if err := needsPlayer(); err != nil {
+			return err
+		}
+		player.Abandoned = true
+		g.record(EventSeatReleased, player.Name, nil)
+
+	case EventPlayerKicked:
+		if err := needsPlayer(); err != nil {
+			return err
+		}
+		player.Kicked = true
+		g.record(EventPlayerKicked, player.Name, nil)
+
+	case EventGameEnded:
+		var reason EndReason
+		if err := json.Unmarshal(entry.Data, &reason); err != nil {
+			return err
+		}
+		g.abort(reason)
+
+	default:
+		return ErrUnknownCommand
+	}
+	return nil
+}
+
+// setCommandLog branche le journal de la partie
+func (g *Game) setCommandLog(commandLog CommandLog) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.commandLog = commandLog
+}
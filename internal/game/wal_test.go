@@ -0,0 +1,131 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// failingLog refuse toute écriture, comme un disque plein
+type failingLog struct{}
+
+func (failingLog) Append(string, Event) error      { return errors.New("disk full") }
+func (failingLog) Load(string) ([]LogEntry, error) { return nil, nil }
+func (failingLog) GameIDs() ([]string, error)      { return nil, nil }
+func (failingLog) Delete(string) error             { return nil }
+
+func TestCommandRejectedWhenLogWriteFails(t *testing.T) {
+	g := newTestGame(t, 4, 1)
+	honor := totalHonor(g)
+	g.commandLog = failingLog{}
+
+	for step := 0; step < 50 && g.State == GameStateStarted; step++ {
+		before := state(g)
+		player := g.Players[g.waitingOn()]
+		g.mu.Lock()
+		cmd := g.botCommand(player)
+		g.mu.Unlock()
+
+		if err := g.Apply(cmd); !errors.Is(err, ErrLogWrite) {
+			t.Fatalf("apply %+v with a failing log: got %v, want %v", cmd, err, ErrLogWrite)
+		}
+		if after := state(g); after != before {
+			t.Fatalf("command %+v was kept although it was not logged", cmd)
+		}
+		checkInvariants(t, g, honor)
+
+		// Le même coup passe une fois le journal rétabli
+		g.commandLog = nil
+		if err := g.Apply(cmd); err != nil {
+			t.Fatalf("apply %+v without a log: %v", cmd, err)
+		}
+		g.commandLog = failingLog{}
+	}
+}
+
+// memoryLog garde les événements en mémoire et refuse d'écrire tant que full est vrai
+type memoryLog struct {
+	full   bool
+	events []Event
+}
+
+func (l *memoryLog) Append(_ string, event Event) error {
+	if l.full {
+		return errors.New("disk full")
+	}
+	l.events = append(l.events, event)
+	return nil
+}
+func (l *memoryLog) Load(string) ([]LogEntry, error) {
+	data, err := json.Marshal(l.events)
+	if err != nil {
+		return nil, err
+	}
+	var entries []LogEntry
+	return entries, json.Unmarshal(data, &entries)
+}
+func (l *memoryLog) GameIDs() ([]string, error) { return nil, nil }
+func (l *memoryLog) Delete(string) error {
+	l.events = nil
+	return nil
+}
+
+func TestChangesRolledBackWhenLogWriteFails(t *testing.T) {
+	g := newTestGame(t, 4, 1)
+	g.Rules.BotAfterTimeouts = 1
+	g.manualBots = true
+	log := &memoryLog{}
+	g.commandLog = log
+
+	changes := []struct {
+		name   string
+		change func() bool
+	}{
+		{"pause", func() bool { return g.Pause("test") == nil }},
+		{"vote pause", func() bool { _, err := g.VotePause("p2"); return err == nil }},
+		{"disconnect", func() bool { return g.MarkDisconnected("p3") }},
+		{"kick", func() bool { return g.Kick("p4") }},
+		{"timeout", func() bool { seq := g.LastSeq(); g.expire(g.timerGeneration); return g.LastSeq() != seq }},
+		{"abort", func() bool { return g.Abort(EndReasonModerator) }},
+	}
+	for _, c := range changes {
+		before := state(g)
+		log.full = true
+		if c.change() {
+			t.Fatalf("%s succeeded although its event was not logged", c.name)
+		}
+		if after := state(g); after != before {
+			t.Fatalf("%s was kept although it was not logged", c.name)
+		}
+
+		// Une fois le journal rétabli, le même changement prend le numéro suivant
+		log.full = false
+		seq := g.LastSeq()
+		if !c.change() {
+			t.Fatalf("%s failed with a working log", c.name)
+		}
+		if first := log.events[len(log.events)-int(g.LastSeq()-seq)]; first.Seq != seq+1 {
+			t.Fatalf("%s logged seq %d after seq %d", c.name, first.Seq, seq)
+		}
+		if c.name == "pause" {
+			g.Resume("test")
+		}
+	}
+	for i, event := range log.events[1:] {
+		if event.Seq != log.events[i].Seq+1 {
+			t.Fatalf("log jumps from seq %d to %d", log.events[i].Seq, event.Seq)
+		}
+	}
+}
+
+func TestJoinRolledBackWhenLogWriteFails(t *testing.T) {
+	g := NewGame("test")
+	g.commandLog = failingLog{}
+	before := state(g)
+	if err := g.AddPlayer(NewPlayer("p1", 0)); !errors.Is(err, ErrLogWrite) {
+		t.Fatalf("join with a failing log: got %v, want %v", err, ErrLogWrite)
+	}
+	if after := state(g); after != before || g.HasPlayer("p1") {
+		t.Fatal("join was kept although it was not logged")
+	}
+}
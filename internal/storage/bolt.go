@@ -0,0 +1,265 @@
+package storage
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/becaraya/katana-api/internal/account"
+	"github.com/becaraya/katana-api/internal/game"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	gamesBucket    = []byte("games")
+	archiveBucket  = []byte("archive")
+	accountsBucket = []byte("accounts")
+	tokensBucket   = []byte("tokens")
+)
+
+// BoltStore est un stockage sur disque dans un fichier bbolt
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// OpenBolt ouvre ou crée le fichier de stockage
+func OpenBolt(path string) (*BoltStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{gamesBucket, archiveBucket, accountsBucket, tokensBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltStore{db: db}, nil
+}
+
+// Close ferme le fichier de stockage
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// Games retourne le stockage des parties en cours
+func (s *BoltStore) Games() *BoltGameRepository {
+	return &BoltGameRepository{db: s.db}
+}
+
+// Archive retourne l'archive des parties terminées
+func (s *BoltStore) Archive() *BoltArchive {
+	return &BoltArchive{db: s.db}
+}
+
+// BoltGameRepository stocke un snapshot par partie, chaque sauvegarde est une transaction
+type BoltGameRepository struct {
+	db *bolt.DB
+}
+
+func (r *BoltGameRepository) Save(snapshot *game.Snapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(gamesBucket).Put([]byte(snapshot.ID), data)
+	})
+}
+
+func (r *BoltGameRepository) Delete(id string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(gamesBucket).Delete([]byte(id))
+	})
+}
+
+func (r *BoltGameRepository) LoadAll() ([]*game.Snapshot, error) {
+	var snapshots []*game.Snapshot
+	err := r.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(gamesBucket).ForEach(func(_, data []byte) error {
+			var snapshot game.Snapshot
+			if err := json.Unmarshal(data, &snapshot); err != nil {
+				return err
+			}
+			snapshots = append(snapshots, &snapshot)
+			return nil
+		})
+	})
+	return snapshots, err
+}
+
+// BoltArchive conserve les parties terminées sur disque
+type BoltArchive struct {
+	db *bolt.DB
+}
+
+func (a *BoltArchive) Save(archived *game.ArchivedGame) error {
+	data, err := json.Marshal(archived)
+	if err != nil {
+		return err
+	}
+	return a.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(archiveBucket).Put([]byte(archived.ID), data)
+	})
+}
+
+func (a *BoltArchive) Get(id string) (*game.ArchivedGame, bool) {
+	var archived *game.ArchivedGame
+	err := a.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(archiveBucket).Get([]byte(id))
+		if data == nil {
+			return nil
+		}
+		archived = &game.ArchivedGame{}
+		return json.Unmarshal(data, archived)
+	})
+	if err != nil || archived == nil {
+		return nil, false
+	}
+	return archived, true
+}
+
+func (a *BoltArchive) List() []*game.ArchivedGame {
+	var games []*game.ArchivedGame
+	a.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(archiveBucket).ForEach(func(_, data []byte) error {
+			var archived game.ArchivedGame
+			if err := json.Unmarshal(data, &archived); err == nil {
+				games = append(games, &archived)
+			}
+			return nil
+		})
+	})
+	sort.Slice(games, func(i, j int) bool { return games[i].ArchivedAt.After(games[j].ArchivedAt) })
+	return games
+}
+
+// Accounts retourne le stockage des comptes
+func (s *BoltStore) Accounts() *BoltAccountStore {
+	return &BoltAccountStore{db: s.db}
+}
+
+// BoltAccountStore conserve les comptes sur disque, indexés par nom en minuscules
+type BoltAccountStore struct {
+	db *bolt.DB
+}
+
+func (s *BoltAccountStore) Create(created *account.Account) error {
+	data, err := json.Marshal(created)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(accountsBucket)
+		key := []byte(strings.ToLower(created.Username))
+		if bucket.Get(key) != nil {
+			return account.ErrUsernameTaken
+		}
+		return bucket.Put(key, data)
+	})
+}
+
+func (s *BoltAccountStore) Get(username string) (*account.Account, bool) {
+	var found *account.Account
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(accountsBucket).Get([]byte(strings.ToLower(username)))
+		if data == nil {
+			return nil
+		}
+		found = &account.Account{}
+		return json.Unmarshal(data, found)
+	})
+	if err != nil || found == nil {
+		return nil, false
+	}
+	return found, true
+}
+
+func (s *BoltAccountStore) Update(username string, change func(updated *account.Account) error) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(accountsBucket)
+		key := []byte(strings.ToLower(username))
+		data := bucket.Get(key)
+		if data == nil {
+			return account.ErrAccountNotFound
+		}
+
+		updated := &account.Account{}
+		if err := json.Unmarshal(data, updated); err != nil {
+			return err
+		}
+		if err := change(updated); err != nil {
+			return err
+		}
+		data, err := json.Marshal(updated)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(key, data)
+	})
+}
+
+// Tokens retourne le stockage des tokens émis et révoqués
+func (s *BoltStore) Tokens() *BoltTokenRepository {
+	return &BoltTokenRepository{db: s.db}
+}
+
+// BoltTokenRepository conserve les tokens sur disque, indexés par jti
+type BoltTokenRepository struct {
+	db *bolt.DB
+}
+
+// storedToken est un token enregistré, avec sa révocation
+type storedToken struct {
+	Info    account.TokenInfo `json:"info"`
+	Revoked bool              `json:"revoked"`
+}
+
+func (r *BoltTokenRepository) Save(info account.TokenInfo, revoked bool) error {
+	data, err := json.Marshal(storedToken{Info: info, Revoked: revoked})
+	if err != nil {
+		return err
+	}
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(tokensBucket).Put([]byte(info.ID), data)
+	})
+}
+
+func (r *BoltTokenRepository) Delete(id string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(tokensBucket).Delete([]byte(id))
+	})
+}
+
+func (r *BoltTokenRepository) LoadAll() ([]account.TokenInfo, []account.TokenInfo, error) {
+	var issued, revoked []account.TokenInfo
+	err := r.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(tokensBucket).ForEach(func(_, data []byte) error {
+			var stored storedToken
+			if err := json.Unmarshal(data, &stored); err != nil {
+				return err
+			}
+			if stored.Revoked {
+				revoked = append(revoked, stored.Info)
+			} else {
+				issued = append(issued, stored.Info)
+			}
+			return nil
+		})
+	})
+	return issued, revoked, err
+}
@@ -0,0 +1,199 @@
+package storage
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/becaraya/katana-api/internal/game"
+)
+
+// walExtension est l'extension des journaux, un fichier par partie
+const walExtension = ".log"
+
+const (
+	// recordHeaderSize est la taille de l'en-tête d'un enregistrement : longueur puis CRC32 des données
+	recordHeaderSize = 8
+	// maxRecordSize protège la relecture contre une longueur corrompue
+	maxRecordSize = 1 << 20
+)
+
+var ErrInvalidGameID = errors.New("invalid game id")
+
+// FileCommandLog écrit les événements de chaque partie dans un fichier en ajout seul
+type FileCommandLog struct {
+	dir   string
+	files map[string]*os.File
+	mu    sync.Mutex
+}
+
+// OpenCommandLog ouvre ou crée le dossier des journaux
+func OpenCommandLog(dir string) (*FileCommandLog, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileCommandLog{
+		dir:   dir,
+		files: make(map[string]*os.File),
+	}, nil
+}
+
+// Append ajoute un enregistrement et attend qu'il soit écrit sur le disque
+func (l *FileCommandLog) Append(gameID string, event game.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	record := make([]byte, recordHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(payload))
+	copy(record[recordHeaderSize:], payload)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	file, err := l.file(gameID)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(record); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// Load relit le journal d'une partie et tronque un dernier enregistrement à moitié écrit
+func (l *FileCommandLog) Load(gameID string) ([]game.LogEntry, error) {
+	path, err := l.path(gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	file, err := os.OpenFile(path, os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []game.LogEntry
+	var offset int64
+	header := make([]byte, recordHeaderSize)
+	for {
+		if _, err := io.ReadFull(file, header); err != nil {
+			break
+		}
+		size := binary.BigEndian.Uint32(header[0:4])
+		if size > maxRecordSize {
+			break
+		}
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(file, payload); err != nil {
+			break
+		}
+		if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+			break
+		}
+		var entry game.LogEntry
+		if err := json.Unmarshal(payload, &entry); err != nil {
+			break
+		}
+		entries = append(entries, entry)
+		offset += int64(recordHeaderSize + len(payload))
+	}
+
+	// Tout ce qui suit le dernier enregistrement complet provient d'une écriture interrompue
+	if info, err := file.Stat(); err == nil && info.Size() > offset {
+		if err := file.Truncate(offset); err != nil {
+			return nil, err
+		}
+		if err := file.Sync(); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// GameIDs retourne les parties qui ont un journal
+func (l *FileCommandLog) GameIDs() ([]string, error) {
+	files, err := os.ReadDir(l.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(files))
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), walExtension) {
+			ids = append(ids, strings.TrimSuffix(file.Name(), walExtension))
+		}
+	}
+	return ids, nil
+}
+
+// Delete supprime le journal d'une partie
+func (l *FileCommandLog) Delete(gameID string) error {
+	path, err := l.path(gameID)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if file, exists := l.files[gameID]; exists {
+		file.Close()
+		delete(l.files, gameID)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Close ferme tous les journaux ouverts
+func (l *FileCommandLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var firstErr error
+	for id, file := range l.files {
+		if err := file.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(l.files, id)
+	}
+	return firstErr
+}
+
+// file retourne le journal ouvert en ajout d'une partie, le verrou doit être tenu
+func (l *FileCommandLog) file(gameID string) (*os.File, error) {
+	if file, exists := l.files[gameID]; exists {
+		return file, nil
+	}
+	path, err := l.path(gameID)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	l.files[gameID] = file
+	return file, nil
+}
+
+// path retourne le chemin du journal d'une partie
+func (l *FileCommandLog) path(gameID string) (string, error) {
+	if gameID == "" || gameID != filepath.Base(gameID) || strings.ContainsAny(gameID, `/\`) {
+		return "", ErrInvalidGameID
+	}
+	return filepath.Join(l.dir, gameID+walExtension), nil
+}
@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/becaraya/katana-api/internal/game"
+)
+
+const testGameID = "20260101120000"
+
+// openTestLog ouvre un journal dans un dossier temporaire, fermé à la fin du test
+func openTestLog(t *testing.T, dir string) *FileCommandLog {
+	t.Helper()
+	log, err := OpenCommandLog(dir)
+	if err != nil {
+		t.Fatalf("OpenCommandLog: %v", err)
+	}
+	t.Cleanup(func() { log.Close() })
+	return log
+}
+
+// appendEvents ajoute les événements de numéros first à last
+func appendEvents(t *testing.T, log *FileCommandLog, first, last int64) {
+	t.Helper()
+	for seq := first; seq <= last; seq++ {
+		if err := log.Append(testGameID, game.Event{Seq: seq, Type: game.EventCommand, Player: "p1"}); err != nil {
+			t.Fatalf("Append %d: %v", seq, err)
+		}
+	}
+}
+
+// loadSeqs relit le journal et retourne les numéros de ses événements
+func loadSeqs(t *testing.T, log *FileCommandLog) []int64 {
+	t.Helper()
+	entries, err := log.Load(testGameID)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	seqs := make([]int64, len(entries))
+	for i, entry := range entries {
+		seqs[i] = entry.Seq
+	}
+	return seqs
+}
+
+// checkSeqs vérifie que le journal contient exactement les numéros 1 à last
+func checkSeqs(t *testing.T, seqs []int64, last int64) {
+	t.Helper()
+	if int64(len(seqs)) != last {
+		t.Fatalf("log holds %v, want seqs 1 to %d", seqs, last)
+	}
+	for i, seq := range seqs {
+		if seq != int64(i+1) {
+			t.Fatalf("log holds %v, want seqs 1 to %d", seqs, last)
+		}
+	}
+}
+
+func TestLoadDropsTruncatedRecord(t *testing.T) {
+	dir := t.TempDir()
+	appendEvents(t, openTestLog(t, dir), 1, 3)
+
+	path := filepath.Join(dir, testGameID+walExtension)
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Une écriture interrompue au milieu du dernier enregistrement
+	if err := os.Truncate(path, info.Size()-5); err != nil {
+		t.Fatal(err)
+	}
+
+	checkSeqs(t, loadSeqs(t, openTestLog(t, dir)), 2)
+}
+
+func TestLoadStopsAtCorruptedChecksum(t *testing.T) {
+	dir := t.TempDir()
+	appendEvents(t, openTestLog(t, dir), 1, 3)
+
+	path := filepath.Join(dir, testGameID+walExtension)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Un octet changé dans les données du dernier enregistrement
+	data[len(data)-2] ^= 0xff
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	checkSeqs(t, loadSeqs(t, openTestLog(t, dir)), 2)
+}
+
+func TestAppendAfterTruncatedRecord(t *testing.T) {
+	dir := t.TempDir()
+	appendEvents(t, openTestLog(t, dir), 1, 3)
+
+	path := filepath.Join(dir, testGameID+walExtension)
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Un en-tête écrit sans ses données
+	if _, err := file.Write([]byte{0, 0, 0, 40, 1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	reopened := openTestLog(t, dir)
+	checkSeqs(t, loadSeqs(t, reopened), 3)
+	appendEvents(t, reopened, 4, 5)
+	checkSeqs(t, loadSeqs(t, reopened), 5)
+
+	// La suite reste lisible après une nouvelle ouverture
+	checkSeqs(t, loadSeqs(t, openTestLog(t, dir)), 5)
+}